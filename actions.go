@@ -0,0 +1,71 @@
+package objector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Kinds of interaction steps
+const (
+	// ActionClick clicks the element
+	ActionClick = "click"
+	// ActionType types Action.Text into the element
+	ActionType = "type"
+)
+
+// ActionTimeout bounds how long an action waits for its element to appear
+const ActionTimeout = 5 * time.Second
+
+// Action is an interaction step run in the page after it loads, to drive
+// the app to the state where the secrets live
+type Action struct {
+	Kind string `json:"kind"`
+	// Selector is the CSS selector of the element acted on
+	Selector string `json:"selector"`
+	Text     string `json:"text,omitempty"`
+}
+
+func (a Action) String() string {
+	if a.Kind == ActionType {
+		return fmt.Sprintf("type into %q", a.Selector)
+	}
+	return fmt.Sprintf("%s %q", a.Kind, a.Selector)
+}
+
+// runActions performs actions in order, each waiting for limiter. A failed
+// action is logged and skipped, or returned when strict is set.
+func runActions(ctx context.Context, actions []Action, strict bool, limiter *Limiter, logger *slog.Logger) error {
+	for _, action := range actions {
+		if err := limiter.wait(ctx); err != nil {
+			return err
+		}
+		var step chromedp.Action
+		switch action.Kind {
+		case ActionClick:
+			step = chromedp.Click(action.Selector, chromedp.ByQuery, chromedp.NodeVisible)
+		case ActionType:
+			step = chromedp.SendKeys(action.Selector, action.Text, chromedp.ByQuery, chromedp.NodeVisible)
+		default:
+			return fmt.Errorf("unknown action %q", action.Kind)
+		}
+
+		actionCtx, cancel := context.WithTimeout(ctx, ActionTimeout)
+		err := step.Do(actionCtx)
+		cancel()
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if strict {
+			return fmt.Errorf("%s failed: %w", action, err)
+		}
+		logger.Warn("action failed, continuing", "action", action.String(), "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package objector
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// compilePattern compiles a user supplied pattern. It must mean the same in
+// Go and in the JavaScript of the injected scripts, so syntax only Go
+// understands is rejected. Go regular expressions run in linear time, but
+// JavaScript's engine backtracks, so patterns with a nested quantifier that
+// can take a value apart in exponentially many ways, like (a+)+, are
+// rejected as well.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if err := goOnlySyntax(pattern); err != nil {
+		return nil, err
+	}
+	tree, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	if name := duplicateCapture(tree, map[string]bool{}); name != "" {
+		return nil, fmt.Errorf("capture group name %q is used twice, which JavaScript rejects", name)
+	}
+	if nested := nestedQuantifier(tree); nested != nil {
+		return nil, fmt.Errorf("nested quantifier %s can backtrack catastrophically in JavaScript", nested)
+	}
+	return re, nil
+}
+
+// goOnlySyntax returns an error naming the first construct of pattern, a
+// valid Go regular expression, that JavaScript rejects or reads differently
+// without flags, such as (?i) or \z
+func goOnlySyntax(pattern string) error {
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		rest := pattern[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1:
+			switch rest[1] {
+			case 'A':
+				return errors.New(`\A is Go-only syntax, use ^ to match the start of the value`)
+			case 'z':
+				return errors.New(`\z is Go-only syntax, use $ to match the end of the value`)
+			case 'Q':
+				return errors.New(`\Q...\E is Go-only syntax, escape each special character instead`)
+			case 'p', 'P':
+				return fmt.Errorf(`%s Unicode classes are Go-only syntax, JavaScript reads them as a letter without the u flag`, rest[:2])
+			case 'x':
+				if len(rest) > 2 && rest[2] == '{' {
+					return errors.New(`\x{...} is Go-only syntax, use \xHH or \uHHHH`)
+				}
+			}
+			i++
+		case inClass:
+			if strings.HasPrefix(rest, "[:") && strings.Contains(rest[2:], ":]") {
+				return errors.New("POSIX classes like [[:alpha:]] are Go-only syntax, list the characters such as [A-Za-z]")
+			}
+			if rest[0] == ']' {
+				inClass = false
+			}
+		case strings.HasPrefix(rest, "[]") || strings.HasPrefix(rest, "[^]"):
+			// JavaScript reads [] as matching nothing and [^] as anything
+			return errors.New("a ] first in a class is Go-only syntax, escape it as \\]")
+		case rest[0] == '[':
+			inClass = true
+		case strings.HasPrefix(rest, "(?P<"):
+			return errors.New("(?P<name>...) is Go-only syntax, use (?<name>...)")
+		case strings.HasPrefix(rest, "(?") && len(rest) > 2 && strings.ContainsRune("imsU-", rune(rest[2])):
+			return errors.New("flag groups like (?i) are Go-only syntax, use a character class such as [Aa] in place of (?i)")
+		}
+	}
+	return nil
+}
+
+// duplicateCapture returns the first capture group name used twice in re,
+// which Go allows, or ""
+func duplicateCapture(re *syntax.Regexp, names map[string]bool) string {
+	if re.Op == syntax.OpCapture && re.Name != "" {
+		if names[re.Name] {
+			return re.Name
+		}
+		names[re.Name] = true
+	}
+	for _, sub := range re.Sub {
+		if name := duplicateCapture(sub, names); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// nestedQuantifier returns the first unbounded repeat in re whose body can
+// be nothing but another unbounded repeat, or nil. Delimited repeats such
+// as ([a-z]+,)* are fine, a nested repeat with an overlapping alternation is
+// not caught.
+func nestedQuantifier(re *syntax.Regexp) *syntax.Regexp {
+	if unbounded(re) && bareRepeat(re.Sub[0]) {
+		return re
+	}
+	for _, sub := range re.Sub {
+		if nested := nestedQuantifier(sub); nested != nil {
+			return nested
+		}
+	}
+	return nil
+}
+
+// unbounded reports whether re repeats its body without limit
+func unbounded(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		return true
+	case syntax.OpRepeat:
+		return re.Max == -1
+	}
+	return false
+}
+
+// bareRepeat reports whether re can match as a single unbounded repeat,
+// everything around it matching the empty string
+func bareRepeat(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpCapture:
+		return bareRepeat(re.Sub[0])
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if bareRepeat(sub) {
+				return true
+			}
+		}
+		return false
+	case syntax.OpConcat:
+		for i, sub := range re.Sub {
+			if !bareRepeat(sub) {
+				continue
+			}
+			rest := true
+			for j, other := range re.Sub {
+				if j != i && !nullable(other) {
+					rest = false
+					break
+				}
+			}
+			if rest {
+				return true
+			}
+		}
+		return false
+	}
+	return unbounded(re)
+}
+
+// nullable reports whether re can match the empty string
+func nullable(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpStar, syntax.OpQuest,
+		syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	case syntax.OpCapture, syntax.OpPlus:
+		return nullable(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min == 0 || nullable(re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !nullable(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if nullable(sub) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
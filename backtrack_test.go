@@ -0,0 +1,141 @@
+package objector
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"testing"
+)
+
+func TestCompilePatternRejectsGoOnlySyntax(t *testing.T) {
+	tests := []struct {
+		pattern, err string
+	}{
+		{`(?i)secret`, "flag groups"},
+		{`(?s)a.b`, "flag groups"},
+		{`(?m)^a$`, "flag groups"},
+		{`(?U)a+`, "flag groups"},
+		{`(?i:secret)`, "flag groups"},
+		{`(?-s:a.b)`, "flag groups"},
+		{`(?P<key>[0-9]+)`, "(?P<name>...)"},
+		{`\Atoken`, `\A`},
+		{`token\z`, `\z`},
+		{`\Q.*\E`, `\Q`},
+		{`\pL+`, "Unicode classes"},
+		{`\p{Greek}+`, "Unicode classes"},
+		{`[\PL]`, "Unicode classes"},
+		{`\x{41}`, `\x{...}`},
+		{`[[:alpha:]]+`, "POSIX classes"},
+		{`[x[:digit:]]`, "POSIX classes"},
+		{`(?<a>x)(?<a>y)`, "used twice"},
+		{`[]:]+`, "first in a class"},
+		{`[^]x]`, "first in a class"},
+		{`(a+)+`, "nested quantifier"},
+		{`^(a*)*$`, "nested quantifier"},
+		{`(\w+\s?)+`, "nested quantifier"},
+	}
+	for _, tt := range tests {
+		_, err := compilePattern(tt.pattern)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("compilePattern(%q) error = %v, want one mentioning %q", tt.pattern, err, tt.err)
+		}
+	}
+}
+
+func TestCompilePatternAcceptsSharedSyntax(t *testing.T) {
+	for _, pattern := range []string{
+		`AKIA[0-9A-Z]{16}`,
+		`(?<key>[0-9]+)`,
+		`(?:ab)+c`,
+		`^token$`,
+		`\\z\\A`,
+		`[\]:]+`,
+		`[^\]x]`,
+		`[\[:]alpha:]`,
+		`\x41B`,
+		`[Pp]assword`,
+		`(?<a>x)|y`,
+	} {
+		if _, err := compilePattern(pattern); err != nil {
+			t.Errorf("compilePattern(%q) failed: %v", pattern, err)
+		}
+	}
+}
+
+func TestDefaultPatternsShareSyntax(t *testing.T) {
+	for name, p := range NewObjectMonitor().patterns {
+		if err := goOnlySyntax(p.pattern); err != nil {
+			t.Errorf("default pattern %s: %v", name, err)
+		}
+		tree, err := syntax.Parse(p.pattern, syntax.Perl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dup := duplicateCapture(tree, map[string]bool{}); dup != "" {
+			t.Errorf("default pattern %s uses capture name %q twice", name, dup)
+		}
+	}
+}
+
+func TestScanScriptReportsPatternCompileError(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is needed to run the scan script")
+	}
+	// Chrome also rejects duplicate names, which AddPattern refuses, so the
+	// pattern is set directly
+	m := NewObjectMonitor()
+	m.ClearPatterns()
+	m.patterns["Bad"] = monitoredPattern{pattern: `(?<n>a)(?<n>b)`, re: regexp.MustCompile(`(?<n>a)(?<n>b)`)}
+
+	cmd := exec.Command(node, "-e", "process.stdout.write(eval(require('fs').readFileSync(0, 'utf8')))")
+	cmd.Stdin = strings.NewReader(m.GetScanScript())
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running the scan script failed: %v", err)
+	}
+	_, err = parseScanResponse(string(out))
+	if !errors.Is(err, ErrPatternCompile) || !strings.Contains(err.Error(), `"Bad"`) {
+		t.Errorf("parseScanResponse error = %v, want ErrPatternCompile naming the pattern", err)
+	}
+}
+
+func TestScanScriptDisablesSlowPattern(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is needed to run the scan script")
+	}
+	// An overlapping alternation backtracks exponentially too, but has no
+	// nested quantifier, so only the timing guard catches it
+	const pattern = `^(a|a)*$`
+	if _, err := compilePattern(pattern); err != nil {
+		t.Fatalf("compilePattern(%q) failed: %v", pattern, err)
+	}
+	m := NewObjectMonitor()
+	m.ClearPatterns()
+	m.patterns["Slow"] = monitoredPattern{pattern: pattern, re: regexp.MustCompile(pattern)}
+
+	// Each value takes the pattern well over a millisecond to give up on,
+	// so lowering the slow time keeps the test fast on any machine
+	script := strings.Replace(m.GetScanScript(), `"slowPatternTime":100`, `"slowPatternTime":1`, 1)
+	if script == m.GetScanScript() {
+		t.Fatal("the scan script has no slowPatternTime of 100 to lower")
+	}
+	setup := "for (let i = 0; i < 5; i++) globalThis['value' + i] = 'a'.repeat(20) + '!';"
+	cmd := exec.Command(node, "-e", setup+"process.stdout.write(eval(require('fs').readFileSync(0, 'utf8')))")
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running the scan script failed: %v", err)
+	}
+	response, err := parseScanResponse(string(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The pattern is skipped once it was slow maxSlowRuns times
+	if got := response.Stats.SlowPatterns["Slow"]; got != maxSlowRuns {
+		t.Errorf("slow evaluations of the pattern = %d, want %d before it is skipped", got, maxSlowRuns)
+	}
+}
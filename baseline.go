@@ -0,0 +1,135 @@
+package objector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Baseline is a set of accepted findings, such as known test keys, that
+// scans leave out. Each is stored as its pattern, path and a hash of its
+// value, so the baseline file can be committed without leaking the secrets.
+type Baseline struct {
+	mu      sync.Mutex
+	entries map[BaselineEntry]bool
+}
+
+// BaselineEntry is one accepted finding
+type BaselineEntry struct {
+	Pattern string `json:"pattern"`
+	Path    string `json:"path"`
+	// Hash is the hex encoded SHA-256 of the value as reported
+	Hash string `json:"hash"`
+}
+
+// baselineFile is the JSON document a Baseline is saved as
+type baselineFile struct {
+	Version int             `json:"version"`
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// NewBaseline returns an empty baseline
+func NewBaseline() *Baseline {
+	return &Baseline{entries: make(map[BaselineEntry]bool)}
+}
+
+// LoadBaseline reads a baseline saved by Save
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file baselineFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("parsing %s: unsupported baseline version %d", path, file.Version)
+	}
+	b := NewBaseline()
+	for _, entry := range file.Entries {
+		b.entries[entry] = true
+	}
+	return b, nil
+}
+
+// Add accepts match, at each of its paths
+func (b *Baseline) Add(match Match) {
+	paths := match.Paths
+	if len(paths) == 0 {
+		paths = []string{match.Path}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, path := range paths {
+		b.entries[baselineEntry(match.Pattern, path, match.Value)] = true
+	}
+}
+
+// Contains reports whether match at its path was accepted
+func (b *Baseline) Contains(match Match) bool {
+	return b.contains(match.Pattern, match.Path, match.Value)
+}
+
+func (b *Baseline) contains(pattern, path, value string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.entries[baselineEntry(pattern, path, value)]
+}
+
+// Len returns the number of accepted findings
+func (b *Baseline) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Save writes the baseline to path, sorted so it diffs well under version
+// control, replacing it atomically
+func (b *Baseline) Save(path string) error {
+	b.mu.Lock()
+	file := baselineFile{Version: 1, Entries: make([]BaselineEntry, 0, len(b.entries))}
+	for entry := range b.entries {
+		file.Entries = append(file.Entries, entry)
+	}
+	b.mu.Unlock()
+	sort.Slice(file.Entries, func(i, j int) bool {
+		a, b := file.Entries[i], file.Entries[j]
+		if a.Pattern != b.Pattern {
+			return a.Pattern < b.Pattern
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Hash < b.Hash
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// baselineEntry is the entry accepting value at path
+func baselineEntry(pattern, path, value string) BaselineEntry {
+	sum := sha256.Sum256([]byte(value))
+	return BaselineEntry{Pattern: pattern, Path: path, Hash: hex.EncodeToString(sum[:])}
+}
@@ -0,0 +1,205 @@
+package objector
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ErrChromeNotFound is returned when the browser cannot be launched because
+// no Chrome or Chromium executable was found, or Options.ChromePath does not
+// name one
+var ErrChromeNotFound = errors.New("Chrome executable not found")
+
+// requiredChromeFlags are the browser flags the scanner relies on
+var requiredChromeFlags = map[string]interface{}{
+	"headless":              true,
+	"disable-gpu":           true,
+	"no-sandbox":            true,
+	"disable-dev-shm-usage": true,
+	"log-level":             "3", // Suppress all logging
+	"silent":                true,
+}
+
+// HTTP versions Options.HTTPVersion can force
+const (
+	HTTPVersion1 = "1.1"
+	HTTPVersion2 = "2"
+	HTTPVersion3 = "3"
+)
+
+// allocatorOptions returns the options for launching the browser to scan
+// target
+func allocatorOptions(opts Options, target string) ([]chromedp.ExecAllocatorOption, error) {
+	// Create a new context with options to suppress errors
+	allocOpts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	for _, name := range sortedKeys(requiredChromeFlags) {
+		allocOpts = append(allocOpts, chromedp.Flag(name, requiredChromeFlags[name]))
+	}
+
+	if err := ValidateChromeFlags(opts.ChromeFlags, opts.OverrideChromeFlags); err != nil {
+		return nil, err
+	}
+	if opts.Insecure {
+		allocOpts = append(allocOpts, chromedp.Flag("ignore-certificate-errors", true))
+	}
+
+	proxy, err := proxyFlags(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(proxy) {
+		allocOpts = append(allocOpts, chromedp.Flag(name, proxy[name]))
+	}
+
+	version, err := httpVersionFlags(opts.HTTPVersion, target)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(version) {
+		allocOpts = append(allocOpts, chromedp.Flag(name, version[name]))
+	}
+
+	for _, name := range sortedKeys(opts.ChromeFlags) {
+		allocOpts = append(allocOpts, chromedp.Flag(name, opts.ChromeFlags[name]))
+	}
+	if opts.ChromePath != "" {
+		allocOpts = append(allocOpts, chromedp.ExecPath(opts.ChromePath))
+	}
+	return allocOpts, nil
+}
+
+// launchError wraps err in ErrChromeNotFound when it is the failure to
+// start the browser executable
+func launchError(err error) error {
+	var execErr *exec.Error
+	var pathErr *fs.PathError
+	if errors.As(err, &execErr) || (errors.As(err, &pathErr) && pathErr.Op == "fork/exec") {
+		return fmt.Errorf("%w: %v", ErrChromeNotFound, err)
+	}
+	return err
+}
+
+// proxyFlags returns the browser flags for the configured proxy. Proxy
+// rules take precedence over a single proxy, and a PAC file cannot be
+// combined with either.
+func proxyFlags(opts Options) (map[string]interface{}, error) {
+	flags := make(map[string]interface{})
+	if opts.ProxyPAC != "" {
+		if opts.Proxy != "" || opts.ProxyRules != "" {
+			return nil, fmt.Errorf("a proxy PAC file cannot be combined with a proxy or proxy rules")
+		}
+		pac := opts.ProxyPAC
+		// Chrome only takes a URL, so a local file is turned into one
+		if !strings.Contains(pac, "://") && !strings.HasPrefix(pac, "data:") {
+			path, err := filepath.Abs(pac)
+			if err != nil {
+				return nil, err
+			}
+			pac = (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+		}
+		flags["proxy-pac-url"] = pac
+		return flags, nil
+	}
+
+	// Both use Chrome's --proxy-server, which takes a single proxy as well
+	// as per-scheme rules
+	switch {
+	case opts.ProxyRules != "":
+		flags["proxy-server"] = opts.ProxyRules
+	case opts.Proxy != "":
+		flags["proxy-server"] = opts.Proxy
+	}
+	return flags, nil
+}
+
+// httpVersionFlags returns the browser flags forcing an HTTP version for
+// target. HTTP/3 is only forced for the origin of target, other origins
+// use it when they advertise it.
+func httpVersionFlags(version, target string) (map[string]interface{}, error) {
+	flags := make(map[string]interface{})
+	switch version {
+	case "":
+	case HTTPVersion1:
+		flags["disable-http2"] = true
+		flags["disable-quic"] = true
+	case HTTPVersion2:
+		flags["disable-quic"] = true
+	case HTTPVersion3:
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+			return nil, fmt.Errorf("HTTP/3 can only be forced for an https URL, not %q", target)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "443"
+		}
+		flags["enable-quic"] = true
+		flags["origin-to-force-quic-on"] = net.JoinHostPort(u.Hostname(), port)
+	default:
+		return nil, fmt.Errorf("unknown HTTP version %q", version)
+	}
+	return flags, nil
+}
+
+// negotiatedHTTPVersion returns the HTTP version of a protocol reported by
+// the browser, such as "h2", or "" for other protocols such as data
+func negotiatedHTTPVersion(protocol string) string {
+	switch {
+	case protocol == "http/1.1":
+		return HTTPVersion1
+	case protocol == "h2":
+		return HTTPVersion2
+	case protocol == "h3" || strings.HasPrefix(protocol, "h3-"):
+		return HTTPVersion3
+	}
+	return ""
+}
+
+// ValidateChromeFlags checks that flags leave the browser flags the scanner
+// relies on alone, unless override is set
+func ValidateChromeFlags(flags map[string]interface{}, override bool) error {
+	if override {
+		return nil
+	}
+	for _, name := range sortedKeys(flags) {
+		if _, required := requiredChromeFlags[name]; required {
+			return fmt.Errorf("chrome flag %q is required by the scanner and can only be changed with an explicit override", name)
+		}
+	}
+	return nil
+}
+
+// ParseChromeFlag parses a name=value browser flag. A bare name enables a
+// boolean flag, and the values true and false are booleans.
+func ParseChromeFlag(flag string) (string, interface{}, error) {
+	name, value, hasValue := strings.Cut(strings.TrimLeft(flag, "-"), "=")
+	if name == "" {
+		return "", nil, fmt.Errorf("invalid chrome flag %q", flag)
+	}
+	switch {
+	case !hasValue || value == "true":
+		return name, true, nil
+	case value == "false":
+		return name, false, nil
+	default:
+		return name, value, nil
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
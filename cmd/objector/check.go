@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// runCheck compiles every active pattern and sends a HEAD request to each
+// target, or checks that a local file exists, without starting a browser, printing one line per check. It
+// reports whether every check passed.
+func runCheck(w io.Writer, targets []string, opts objector.Options) bool {
+	ok := true
+
+	// Patterns are compiled as they are added
+	fmt.Fprintln(w, "Patterns:")
+	monitor := objector.NewObjectMonitor()
+	if opts.NoDefaultPatterns {
+		monitor.ClearPatterns()
+	}
+	for _, p := range opts.Patterns {
+		if err := monitor.AddPattern(p.Name, p.Pattern, p.Description); err != nil {
+			printCheck(w, false, p.Name, err.Error())
+			ok = false
+		}
+	}
+	monitor.SelectPatterns(opts.IncludePatterns, opts.ExcludePatterns)
+	for _, p := range monitor.Patterns() {
+		printCheck(w, true, p.Name, "")
+	}
+
+	fmt.Fprintln(w, "URLs:")
+	client := &http.Client{Timeout: opts.Timeout}
+	for _, target := range targets {
+		check := func() (string, error) { return checkURL(client, target, opts.Headers) }
+		if isLocal(target) {
+			check = func() (string, error) { return checkFile(target) }
+		}
+		status, err := check()
+		if err != nil {
+			printCheck(w, false, target, err.Error())
+			ok = false
+			continue
+		}
+		printCheck(w, true, target, status)
+	}
+	return ok
+}
+
+// checkURL sends a HEAD request to url, falling back to GET for servers
+// that do not support HEAD, and returns the response status
+func checkURL(client *http.Client, url string, headers map[string]string) (string, error) {
+	var resp *http.Response
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(context.Background(), method, url, nil)
+		if err != nil {
+			return "", err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err = client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("server responded with %s", resp.Status)
+	}
+	return resp.Status, nil
+}
+
+// checkFile checks that the file named by a file:// URL can be read
+func checkFile(target string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(u.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d bytes", info.Size()), nil
+}
+
+// printCheck prints the result of a single check
+func printCheck(w io.Writer, ok bool, name, detail string) {
+	if ok {
+		fmt.Fprintf(w, "  "+colorGreen+"OK"+colorReset+"     %s", name)
+	} else {
+		fmt.Fprintf(w, "  "+colorRed+"ERROR"+colorReset+"  %s", name)
+	}
+	if detail != "" {
+		fmt.Fprintf(w, ": %s", detail)
+	}
+	fmt.Fprintln(w)
+}
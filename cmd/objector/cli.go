@@ -0,0 +1,689 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// cliFlags holds the command line flags
+type cliFlags struct {
+	targets                          stringList
+	scanFiles, scanScripts           stringList
+	readStdin                        bool
+	configFile                       string
+	printConfig                      bool
+	listPatterns                     bool
+	noDefaultPatterns                bool
+	patternFiles                     stringList
+	check                            bool
+	timeout                          time.Duration
+	grace                            time.Duration
+	maxRuntime                       time.Duration
+	headers                          string
+	headersFile                      string
+	bearer                           string
+	customStrings, customRegexes     stringList
+	ignoreCase                       bool
+	includePatterns, excludePatterns stringList
+	minSeverity                      string
+	failOn                           string
+	failOnMatch                      bool
+	countOnly                        bool
+	deepScan                         bool
+	decodeBase64                     bool
+	once                             bool
+	sinceStart                       bool
+	interval                         time.Duration
+	scanBudget                       int
+	scanTimeBudget                   time.Duration
+	jsMatchCap                       int
+	minValueLength                   int
+	maxValueLength                   int
+	maxValueSize                     int
+	verifyAWS                        bool
+	validatorCommand                 string
+	validatorConcurrency             int
+	validatorTimeout                 time.Duration
+	contextChars                     int
+	dedupBy                          string
+	emitOnChange                     bool
+	showSummary                      bool
+	noStats                          bool
+	outputDir                        string
+	redact                           bool
+	redactAll                        bool
+	compact                          bool
+	format                           string
+	sortOrder                        string
+	templateText, templateFile       string
+	showTimestamp                    bool
+	timestampFormat                  string
+	showID                           bool
+	retries                          int
+	concurrency                      int
+	excludePaths                     stringList
+	noFollowRedirects                bool
+	scopeFile                        string
+	respectRobots                    bool
+	maxMatches                       int
+	waitForMatch                     countFlag
+	maxMatchesPerPattern             int
+	maxDedupEntries                  int
+	rate                             float64
+	delay                            time.Duration
+	chromeFlags                      stringList
+	insecure                         bool
+	proxy                            string
+	proxyRules                       string
+	proxyPAC                         string
+	httpVersion                      string
+	remoteChrome                     string
+	chromePath                       string
+	overrideChromeFlags              bool
+	scanStorage                      bool
+	scanDOM                          bool
+	scanSourceMaps                   bool
+	scanFrames                       bool
+	domains                          string
+	debug                            bool
+	logLevel                         string
+	profile                          bool
+	cpuProfile                       string
+	screenshotDir                    string
+	screenshotAll                    bool
+	postLoadScript                   string
+	actions                          []objector.Action
+	strict                           bool
+	diffBaseline                     string
+	diffBy                           string
+	stateFile                        string
+	baselineFile                     string
+	baselineGenerate                 string
+	harFile                          string
+	harMaxBody                       int
+	webhookURL                       string
+	webhookHeaders                   stringList
+	colorMode                        string
+	asciiOutput                      bool
+	help, helpShort                  bool
+
+	// config is the config file, empty without --config
+	config *objector.Config
+	// fromEnv is the number of flags set from the environment
+	fromEnv int
+}
+
+// parseFlags defines the flags on fs and parses args, then sets the flags
+// not given from the environment and the config file, in that order
+func parseFlags(fs *flag.FlagSet, args []string) (*cliFlags, error) {
+	f := &cliFlags{}
+	fs.Var(&f.targets, "u", "URL to monitor (required, repeatable)")
+	fs.Var(&f.scanFiles, "scan-file", "Local HTML file to scan, loaded into a blank page (repeatable)")
+	fs.BoolVar(&f.readStdin, "stdin", false, "Read URLs to scan from stdin, one per line, scanning each as it arrives")
+	fs.Var(&f.scanScripts, "scan-js", "Local JavaScript file to run in a blank page and scan (repeatable)")
+	fs.StringVar(&f.configFile, "config", "", "JSON file with extra patterns, ignored paths, max depth and options")
+	fs.BoolVar(&f.printConfig, "print-config", false, "Print the effective configuration, secrets redacted, and exit")
+	fs.BoolVar(&f.listPatterns, "list-patterns", false, "Print the patterns a scan would run and exit")
+	fs.BoolVar(&f.noDefaultPatterns, "no-default-patterns", false, "Only monitor the patterns from --config and --pattern-file")
+	fs.Var(&f.patternFiles, "pattern-file", "JSON or CSV file with extra patterns only (repeatable)")
+	fs.BoolVar(&f.check, "check", false, "Only check that the patterns compile and the URLs respond")
+	fs.Var(&f.targets, "url", "URL to monitor (required, repeatable)")
+	fs.DurationVar(&f.timeout, "timeout", 20*time.Second, "Monitoring timeout")
+	fs.DurationVar(&f.grace, "grace", 2*time.Second, "Time after the timeout to record the last matches, 0 to stop at once")
+	fs.DurationVar(&f.maxRuntime, "max-runtime", 0, "Stop the whole run after this long and kill it if it hangs (0 for no limit)")
+	fs.StringVar(&f.headers, "headers", "", "Headers to include in requests (format: 'HEADER: VALUE,HEADER2: VALUE2')")
+	fs.StringVar(&f.headersFile, "headers-file", "", "File of headers to include in requests, one 'Name: Value' per line")
+	fs.StringVar(&f.bearer, "bearer", "", "Token sent as 'Authorization: Bearer <token>' with requests")
+	fs.Var(&f.customStrings, "string", "Custom string to search for, instead of the patterns (repeatable)")
+	fs.BoolVar(&f.ignoreCase, "ignore-case", false, "Match --string searches in any letter case")
+	fs.Var(&f.customRegexes, "string-regex", "Custom regular expression to search for, instead of the patterns (repeatable)")
+	fs.Var(&f.includePatterns, "include-pattern", "Only run the named pattern (repeatable)")
+	fs.Var(&f.excludePatterns, "exclude-pattern", "Do not run the named pattern (repeatable)")
+	fs.StringVar(&f.minSeverity, "min-severity", "", "Only report matches at least this severe: critical, high, medium, low")
+	fs.StringVar(&f.failOn, "fail-on", "", "Exit with status 3 when a match is at least this severe")
+	fs.BoolVar(&f.failOnMatch, "fail-on-match", false, "Exit with status 3 when any match is found")
+	fs.BoolVar(&f.countOnly, "count-only", false, "Only print the number of unique matches")
+	fs.BoolVar(&f.deepScan, "deep-scan", false, "Also scan non-enumerable properties and getters (getters may have side effects)")
+	fs.BoolVar(&f.decodeBase64, "decode-base64", false, "Also scan what long base64 strings decode to")
+	fs.BoolVar(&f.once, "once", false, "Scan each page once after it loads instead of monitoring it")
+	fs.BoolVar(&f.sinceStart, "since-start", false, "Only report matches that appear after the first scan of each page")
+	fs.DurationVar(&f.interval, "interval", objector.DefaultScanInterval, "Time between scan passes")
+	fs.IntVar(&f.scanBudget, "scan-budget", objector.DefaultScanBudget, "Objects visited per scan pass (0 for no limit)")
+	fs.DurationVar(&f.scanTimeBudget, "scan-time-budget", objector.DefaultScanTimeBudget, "Time allowed per scan pass (0 for no limit)")
+	fs.IntVar(&f.jsMatchCap, "js-match-cap", objector.DefaultJSMatchCap, "Matches held in the page by a scan pass or the monitor (0 for no limit)")
+	fs.IntVar(&f.minValueLength, "min-value-length", 0, "Ignore matched values shorter than n characters")
+	fs.IntVar(&f.maxValueLength, "max-value-length", 0, "Ignore matched values longer than n characters")
+	fs.IntVar(&f.maxValueSize, "max-value-size", objector.DefaultMaxValueSize, "Bytes of a matched value that are reported (0 for no limit)")
+	fs.BoolVar(&f.verifyAWS, "verify-aws", false, "Check AWS access keys found with a secret key by calling sts:GetCallerIdentity (sends the keys to AWS)")
+	fs.StringVar(&f.validatorCommand, "validator", "", "Shell command given each match as JSON on stdin, printing valid, invalid or unknown")
+	fs.IntVar(&f.validatorConcurrency, "validator-concurrency", 4, "Validator commands run at once")
+	fs.DurationVar(&f.validatorTimeout, "validator-timeout", 10*time.Second, "Time a validator command has to answer")
+	fs.IntVar(&f.contextChars, "context-chars", 0, "Characters of the value to report on either side of each match, with the sibling property names")
+	fs.StringVar(&f.dedupBy, "dedup-by", objector.DedupByPath, "Collapse repeat matches by path or value")
+	fs.BoolVar(&f.emitOnChange, "emit-on-change", false, "Report a match whenever the value at a path changes, with the previous value")
+	fs.BoolVar(&f.showSummary, "summary", false, "Print matches grouped by pattern, value and path")
+	fs.BoolVar(&f.noStats, "no-stats", false, "Do not print the statistics after the table")
+	fs.StringVar(&f.outputDir, "output-dir", "", "Also write the result of each URL to its own file in this directory")
+	fs.BoolVar(&f.redact, "redact", false, "Only show the first and last characters of values")
+	fs.BoolVar(&f.redactAll, "redact-full", false, "Replace values with a length placeholder")
+	fs.BoolVar(&f.compact, "compact-value", false, "Collapse runs of whitespace in shown values to single spaces")
+	fs.StringVar(&f.format, "format", formatTable, "Output format: table, json, ndjson, sarif, junit, line, template")
+	fs.StringVar(&f.sortOrder, "sort", sortSeverity, "Order of the reported matches: severity, pattern, path, time")
+	fs.StringVar(&f.templateText, "template", "", "Go text/template for --format template, or the built-in markdown or log")
+	fs.StringVar(&f.templateFile, "template-file", "", "File with the Go text/template for --format template")
+	fs.BoolVar(&f.showTimestamp, "timestamp", false, "Add a time column to the table")
+	fs.StringVar(&f.timestampFormat, "timestamp-format", "", "Go time layout, rfc3339 or unix for match timestamps")
+	fs.BoolVar(&f.showID, "show-id", false, "Add the short match ID to the description column of the table")
+	fs.IntVar(&f.retries, "retries", 0, "Number of times to retry failed page loads")
+	fs.IntVar(&f.concurrency, "concurrency", 1, "Number of URLs scanned at once")
+	fs.Var(&f.excludePaths, "exclude-path", "Glob of object paths to skip, e.g. '**.__reactFiber*' (repeatable)")
+	fs.BoolVar(&f.noFollowRedirects, "no-follow-redirects", false, "Fail a URL that redirects instead of scanning where it leads")
+	fs.StringVar(&f.scopeFile, "scope", "", "File of allowed URL prefixes, one per line")
+	fs.BoolVar(&f.respectRobots, "respect-robots", false, "Skip URLs the robots.txt of their host disallows")
+	fs.IntVar(&f.maxMatches, "max-matches", 0, "Stop scanning a page after n matches (0 for no limit)")
+	fs.Var(&f.waitForMatch, "wait-for-match", "Stop monitoring a page as soon as it has a match, or n matches with --wait-for-match n")
+	fs.IntVar(&f.maxMatchesPerPattern, "max-matches-per-pattern", 0, "Keep n matches of each pattern, stopping once all patterns have n (0 for no limit)")
+	fs.IntVar(&f.maxDedupEntries, "max-dedup-entries", 0, "Remember at most n matches to skip repeats, which may be reported again once forgotten (0 for no limit)")
+	fs.Float64Var(&f.rate, "rate", 0, "Maximum page loads and actions per second")
+	fs.DurationVar(&f.delay, "delay", 0, "Minimum time between page loads and actions")
+	fs.Var(&f.chromeFlags, "chrome-flag", "Extra Chrome command line flag as name=value or name (repeatable)")
+	fs.BoolVar(&f.insecure, "insecure", false, "Ignore TLS certificate errors (connections can then be intercepted)")
+	fs.StringVar(&f.proxy, "proxy", "", "Proxy for all browser traffic, e.g. http://127.0.0.1:8080")
+	fs.StringVar(&f.proxyRules, "proxy-rules", "", "Chrome proxy rules, e.g. 'https=127.0.0.1:8080;http=direct://' (overrides --proxy)")
+	fs.StringVar(&f.proxyPAC, "proxy-pac", "", "URL or path of a PAC file choosing a proxy per host")
+	fs.StringVar(&f.httpVersion, "http-version", "auto", "HTTP version the browser speaks: auto, 1.1, 2 or 3")
+	fs.StringVar(&f.remoteChrome, "remote-chrome", "", "DevTools endpoint of a running Chrome to use instead of launching one")
+	fs.StringVar(&f.chromePath, "chrome-path", "", "Chrome or Chromium executable to launch instead of the one found")
+	fs.BoolVar(&f.overrideChromeFlags, "override-chrome-flags", false, "Allow --chrome-flag to change flags objector relies on")
+	fs.BoolVar(&f.scanStorage, "scan-storage", false, "Also scan localStorage and sessionStorage entries")
+	fs.BoolVar(&f.scanDOM, "scan-dom", false, "Also scan the attribute values of every DOM element")
+	fs.BoolVar(&f.scanSourceMaps, "scan-sourcemaps", false, "Also scan the original sources embedded in the source maps of the page's scripts")
+	fs.BoolVar(&f.scanFrames, "scan-frames", false, "Also scan same-origin iframes")
+	fs.StringVar(&f.domains, "domains", "", "Only scan frames from these comma-separated domains and their subdomains")
+	fs.BoolVar(&f.debug, "debug", false, "Log scanner and browser console diagnostics to stderr")
+	fs.StringVar(&f.logLevel, "log-level", "error", "Lowest level of the diagnostics logged to stderr: debug, info, warn or error")
+	fs.BoolVar(&f.profile, "profile", false, "Report the time spent in each phase of the scan and the scan pass rates")
+	fs.StringVar(&f.cpuProfile, "cpuprofile", "", "File to write a CPU profile of objector to")
+	fs.StringVar(&f.screenshotDir, "screenshot", "", "Directory for full page screenshots taken when matches are found")
+	fs.BoolVar(&f.screenshotAll, "screenshot-all", false, "Also capture every page once loaded (requires --screenshot)")
+	fs.StringVar(&f.postLoadScript, "post-load-script", "", "JavaScript file to run in each page after it loads, before scanning")
+	fs.Var(actionFlag{objector.ActionClick, &f.actions}, "click", "CSS selector to click after the page loads (repeatable)")
+	fs.Var(actionFlag{objector.ActionType, &f.actions}, "type", "Type text after the page loads, as 'selector=text' (repeatable)")
+	fs.BoolVar(&f.strict, "strict", false, "Fail the scan when a --click or --type step fails")
+	fs.StringVar(&f.diffBaseline, "diff", "", "Earlier --format json result to compare the matches with")
+	fs.StringVar(&f.diffBy, "diff-key", "", "Compare matches with the baseline by path or value (default: --dedup-by)")
+	fs.StringVar(&f.stateFile, "state", "", "File of matches seen by earlier runs, only new matches are reported")
+	fs.StringVar(&f.baselineFile, "baseline", "", "Baseline file of accepted findings to leave out")
+	fs.StringVar(&f.baselineGenerate, "baseline-generate", "", "Write the findings of this run to a baseline file, adding to --baseline if given")
+	fs.StringVar(&f.harFile, "har", "", "Record all network requests and responses to this HAR file")
+	fs.IntVar(&f.harMaxBody, "har-max-body", objector.DefaultHARBodySize, "Bytes of each response body kept in the HAR (-1 for none)")
+	fs.StringVar(&f.webhookURL, "webhook", "", "POST each new match as JSON to this URL")
+	fs.Var(&f.webhookHeaders, "webhook-header", "Header for webhook requests as 'Name: Value' (repeatable)")
+	fs.StringVar(&f.colorMode, "color", colorAuto, "Colored output: auto, always, never")
+	fs.BoolVar(&f.asciiOutput, "ascii", false, "Draw borders and the spinner with plain ASCII characters")
+	fs.BoolVar(&f.help, "help", false, "Show help message")
+	fs.BoolVar(&f.helpShort, "h", false, "Show help message")
+
+	if err := fs.Parse(joinCounts(args, "wait-for-match")); err != nil {
+		return nil, err
+	}
+
+	// Flags not given fall back to OBJECTOR_* environment variables, which
+	// keep secrets such as headers out of process listings
+	var err error
+	if f.fromEnv, err = applyEnv(fs); err != nil {
+		return nil, err
+	}
+
+	// Then to the options of the config file
+	f.config = &objector.Config{}
+	if f.configFile != "" {
+		if f.config, err = objector.LoadConfig(f.configFile); err != nil {
+			return nil, fmt.Errorf("could not load config: %w", err)
+		}
+		if err := applyConfig(fs, f.config.Options); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", f.configFile, err)
+		}
+	}
+	for _, path := range f.patternFiles {
+		patterns, err := objector.LoadPatterns(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load patterns: %w", err)
+		}
+		f.config.Patterns = append(f.config.Patterns, patterns...)
+	}
+	return f, nil
+}
+
+// validateFlags checks the flags before anything is scanned
+func validateFlags(f *cliFlags) error {
+	if f.noDefaultPatterns && len(f.config.Patterns) == 0 && len(f.customStrings) == 0 && len(f.customRegexes) == 0 {
+		return errors.New("--no-default-patterns leaves nothing to search for. Add patterns with --config or --pattern-file")
+	}
+	if f.retries < 0 {
+		return errors.New("--retries must not be negative")
+	}
+	if f.concurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+	if f.screenshotAll && f.screenshotDir == "" {
+		return errors.New("--screenshot-all requires --screenshot <dir>")
+	}
+	if !slices.Contains(formats, f.format) {
+		return fmt.Errorf("unknown format %q. Use %s", f.format, strings.Join(formats, ", "))
+	}
+	if f.dedupBy != objector.DedupByPath && f.dedupBy != objector.DedupByValue {
+		return fmt.Errorf("unknown dedup mode %q. Use path or value", f.dedupBy)
+	}
+	if f.minValueLength < 0 || f.maxValueLength < 0 || (f.maxValueLength > 0 && f.minValueLength > f.maxValueLength) {
+		return errors.New("invalid value length range")
+	}
+	if err := validateTimestampFormat(f.timestampFormat); err != nil {
+		return fmt.Errorf("invalid --timestamp-format: %w", err)
+	}
+	if f.waitForMatch > 0 {
+		if f.once {
+			return errors.New("--wait-for-match waits for matches while monitoring and cannot be used with --once")
+		}
+		if f.maxMatches > 0 && f.maxMatches != int(f.waitForMatch) {
+			return errors.New("--wait-for-match and --max-matches set different match counts")
+		}
+	}
+	if f.rate < 0 || f.delay < 0 {
+		return errors.New("--rate and --delay must not be negative")
+	}
+
+	for _, pattern := range f.customRegexes {
+		if err := objector.NewObjectMonitor().AddCustomRegex(pattern); err != nil {
+			return fmt.Errorf("invalid --string-regex: %w", err)
+		}
+	}
+	if f.interval < objector.MinScanInterval {
+		return fmt.Errorf("--interval must be at least %s", objector.MinScanInterval)
+	}
+	if f.diffBaseline != "" {
+		if key := f.diffKey(); key != objector.DedupByPath && key != objector.DedupByValue {
+			return errors.New("--diff-key must be path or value")
+		}
+	}
+	if f.minSeverity != "" {
+		if _, err := objector.ParseSeverity(f.minSeverity); err != nil {
+			return fmt.Errorf("invalid --min-severity: %w", err)
+		}
+	}
+	if f.failOn != "" {
+		if _, err := objector.ParseSeverity(f.failOn); err != nil {
+			return fmt.Errorf("invalid --fail-on: %w", err)
+		}
+		if f.failOnMatch {
+			return errors.New("--fail-on-match fails on any match and cannot be combined with --fail-on")
+		}
+	}
+	if f.countOnly && f.format != formatTable {
+		return errors.New("--count-only prints only the number of matches and cannot be combined with --format")
+	}
+	if !slices.Contains(sortOrders, f.sortOrder) {
+		return fmt.Errorf("unknown sort order %q. Use %s", f.sortOrder, strings.Join(sortOrders, ", "))
+	}
+	if f.maxMatches < 0 || f.maxMatchesPerPattern < 0 {
+		return errors.New("--max-matches and --max-matches-per-pattern must not be negative")
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(f.logLevel)); err != nil {
+		return fmt.Errorf("unknown log level %q. Use debug, info, warn or error", f.logLevel)
+	}
+	if f.maxDedupEntries < 0 {
+		return errors.New("--max-dedup-entries must not be negative")
+	}
+	if f.contextChars < 0 {
+		return errors.New("--context-chars must not be negative")
+	}
+	if f.validatorConcurrency < 1 || f.validatorTimeout <= 0 {
+		return errors.New("--validator-concurrency and --validator-timeout must be positive")
+	}
+	switch f.httpVersion {
+	case "auto", objector.HTTPVersion1, objector.HTTPVersion2, objector.HTTPVersion3:
+	default:
+		return fmt.Errorf("unknown HTTP version %q. Use auto, 1.1, 2 or 3", f.httpVersion)
+	}
+	if f.remoteChrome != "" {
+		if u, err := url.Parse(f.remoteChrome); err != nil || (u.Scheme != "ws" && u.Scheme != "wss" && u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("--remote-chrome must be a ws:// or http:// DevTools endpoint, e.g. http://127.0.0.1:9222")
+		}
+	}
+	if f.grace < 0 {
+		return errors.New("--grace must not be negative")
+	}
+	if f.maxRuntime < 0 {
+		return errors.New("--max-runtime must not be negative")
+	}
+	if f.jsMatchCap < 0 {
+		return errors.New("--js-match-cap must not be negative")
+	}
+	if f.sinceStart && f.once {
+		return errors.New("--since-start reports what appears while monitoring and cannot be used with --once")
+	}
+	return nil
+}
+
+// buildOptions turns validated flags into the options of each scan
+func buildOptions(f *cliFlags) (objector.Options, error) {
+	// Headers of --headers replace the same ones of the file
+	headerMap, err := parseHeaders(f.headers)
+	if err != nil {
+		return objector.Options{}, fmt.Errorf("invalid --headers: %w", err)
+	}
+	if f.headersFile != "" {
+		fileHeaders, err := loadHeaders(f.headersFile)
+		if err != nil {
+			return objector.Options{}, fmt.Errorf("invalid --headers-file: %w", err)
+		}
+		given := make(map[string]bool)
+		for name := range headerMap {
+			given[strings.ToLower(name)] = true
+		}
+		for name, value := range fileHeaders {
+			if !given[strings.ToLower(name)] {
+				headerMap[name] = value
+			}
+		}
+	}
+	if f.bearer != "" {
+		for name := range headerMap {
+			if strings.EqualFold(name, "Authorization") {
+				return objector.Options{}, errors.New("--bearer cannot be used with an Authorization header")
+			}
+		}
+		headerMap["Authorization"] = "Bearer " + f.bearer
+	}
+
+	chromeFlagMap := make(map[string]interface{})
+	for _, chromeFlag := range f.chromeFlags {
+		name, value, err := objector.ParseChromeFlag(chromeFlag)
+		if err != nil {
+			return objector.Options{}, err
+		}
+		chromeFlagMap[name] = value
+	}
+	if err := objector.ValidateChromeFlags(chromeFlagMap, f.overrideChromeFlags); err != nil {
+		return objector.Options{}, fmt.Errorf("%w. Use --override-chrome-flags to change it", err)
+	}
+
+	// The setup script run in each page
+	var setupScript string
+	if f.postLoadScript != "" {
+		data, err := os.ReadFile(f.postLoadScript)
+		if err != nil {
+			return objector.Options{}, fmt.Errorf("could not read post-load script: %w", err)
+		}
+		setupScript = string(data)
+	}
+
+	// The matches reported by earlier runs and the findings accepted earlier
+	var state *objector.State
+	if f.stateFile != "" {
+		if state, err = objector.LoadState(f.stateFile); err != nil {
+			return objector.Options{}, fmt.Errorf("could not load state: %w", err)
+		}
+	}
+	var accepted *objector.Baseline
+	if f.baselineFile != "" {
+		if accepted, err = objector.LoadBaseline(f.baselineFile); err != nil {
+			return objector.Options{}, fmt.Errorf("could not load baseline: %w", err)
+		}
+	}
+
+	// auto leaves the version for Chrome to negotiate
+	httpVersion := f.httpVersion
+	if httpVersion == "auto" {
+		httpVersion = ""
+	}
+	reportSeverity, _ := parseSeverityFlag(f.minSeverity)
+
+	maxMatches := f.maxMatches
+	if f.waitForMatch > 0 {
+		maxMatches = int(f.waitForMatch)
+	}
+
+	opts := objector.Options{
+		Patterns:             f.config.Patterns,
+		NoDefaultPatterns:    f.noDefaultPatterns,
+		IgnoredPaths:         f.config.IgnoredPaths,
+		ExcludePaths:         f.excludePaths,
+		MaxDepth:             f.config.MaxDepth,
+		IncludePatterns:      f.includePatterns,
+		ExcludePatterns:      f.excludePatterns,
+		MinSeverity:          reportSeverity,
+		Headers:              headerMap,
+		Timeout:              f.timeout,
+		Grace:                f.grace,
+		CustomStrings:        f.customStrings,
+		IgnoreCase:           f.ignoreCase,
+		CustomRegexes:        f.customRegexes,
+		DeepScan:             f.deepScan,
+		DecodeBase64:         f.decodeBase64,
+		Once:                 f.once,
+		SinceStart:           f.sinceStart,
+		Interval:             f.interval,
+		ScanBudget:           orUnlimited(f.scanBudget),
+		ScanTimeBudget:       orUnlimited(f.scanTimeBudget),
+		JSMatchCap:           orUnlimited(f.jsMatchCap),
+		MinValueLength:       f.minValueLength,
+		MaxValueLength:       f.maxValueLength,
+		MaxValueSize:         orUnlimited(f.maxValueSize),
+		ContextChars:         f.contextChars,
+		MaxMatches:           maxMatches,
+		MaxMatchesPerPattern: f.maxMatchesPerPattern,
+		MaxDedupEntries:      f.maxDedupEntries,
+		DedupBy:              f.dedupBy,
+		EmitOnChange:         f.emitOnChange,
+		Retries:              f.retries,
+		Limiter:              newLimiter(f.rate, f.delay),
+		NoFollowRedirects:    f.noFollowRedirects,
+		ChromeFlags:          chromeFlagMap,
+		OverrideChromeFlags:  f.overrideChromeFlags,
+		Insecure:             f.insecure,
+		Proxy:                f.proxy,
+		ProxyRules:           f.proxyRules,
+		ProxyPAC:             f.proxyPAC,
+		HTTPVersion:          httpVersion,
+		RemoteChrome:         f.remoteChrome,
+		ChromePath:           f.chromePath,
+		ScanStorage:          f.scanStorage,
+		ScanDOM:              f.scanDOM,
+		ScanSourceMaps:       f.scanSourceMaps,
+		ScanFrames:           f.scanFrames,
+		Domains:              splitList(f.domains),
+		Debug:                f.debug,
+		Profile:              f.profile,
+		Logger:               slog.Default(),
+		ScreenshotDir:        f.screenshotDir,
+		ScreenshotAll:        f.screenshotAll,
+		PostLoadScript:       setupScript,
+		Actions:              f.actions,
+		Strict:               f.strict,
+		State:                state,
+		Baseline:             accepted,
+	}
+
+	// Share one HAR between all targets, a page per URL
+	if f.harFile != "" {
+		opts.HAR = objector.NewHAR(version)
+		opts.HAR.MaxBodySize = f.harMaxBody
+	}
+	return opts, nil
+}
+
+// parseSeverityFlag parses a severity flag, which may be left empty
+func parseSeverityFlag(value string) (objector.Severity, error) {
+	if value == "" {
+		return "", nil
+	}
+	return objector.ParseSeverity(value)
+}
+
+// failSeverity is the severity of a match that fails the run, empty if
+// none does. Every match is at least low.
+func (f *cliFlags) failSeverity() objector.Severity {
+	if f.failOnMatch {
+		return objector.SeverityLow
+	}
+	severity, _ := parseSeverityFlag(f.failOn)
+	return severity
+}
+
+// exitStatus returns the exit status of a run that ended with cause, nil if
+// it ran to the end: 124 if --max-runtime stopped it, 130 if it was
+// interrupted, 3 if --fail-on or --fail-on-match found a match, even from
+// the matches streamed when failMatched is set, 1 if a URL could not be
+// scanned or a result not saved, and 0 otherwise. Findings come before
+// errors, so a CI gate tells a failed check from a failed scan whichever
+// URLs could not be scanned.
+func (f *cliFlags) exitStatus(cause error, r report, failMatched, saveFailed bool) int {
+	switch {
+	case errors.Is(cause, errMaxRuntime):
+		return exitMaxRuntime
+	case cause != nil:
+		return 130
+	}
+	if severity := f.failSeverity(); severity != "" {
+		if failMatched {
+			return 3
+		}
+		for _, m := range r.Matches {
+			if m.Severity.AtLeast(severity) {
+				return 3
+			}
+		}
+	}
+	if len(r.Errors) > 0 || saveFailed {
+		return 1
+	}
+	return 0
+}
+
+// diffKey is what matches are compared with the baseline by, which
+// defaults to how they are collapsed
+func (f *cliFlags) diffKey() string {
+	if f.diffBy == "" {
+		return f.dedupBy
+	}
+	return f.diffBy
+}
+
+// showTime reports whether matches are shown with their time
+func (f *cliFlags) showTime() bool {
+	return f.showTimestamp || f.timestampFormat != ""
+}
+
+// matchLine renders a match for --format line, after its time if shown
+func (f *cliFlags) matchLine(match objector.Match) string {
+	line := formatMatchLine(match)
+	if f.showTime() {
+		line = formatTimestamp(match.Timestamp, f.timestampFormat) + "\t" + line
+	}
+	return line
+}
+
+// redactor is the function applied to every value shown, as selected by
+// --redact and --redact-full
+func (f *cliFlags) redactor() func(string) string {
+	redactValue := func(value string) string { return value }
+	switch {
+	case f.redactAll:
+		redactValue = redactFull
+	case f.redact:
+		redactValue = redactPartial
+	}
+	// Compacted after redaction, so --redact-full counts every character
+	if f.compact {
+		redactVisible := redactValue
+		redactValue = func(value string) string { return compactValue(redactVisible(value)) }
+	}
+	return redactValue
+}
+
+// writeResult writes the result of the scans to w once they are done, in the
+// format of the flags. The table is only written on a terminal, where it is
+// laid out to fit.
+func writeResult(w *os.File, f *cliFlags, r report, tmpl *template.Template, searches []string, interactive bool) error {
+	switch {
+	case f.countOnly:
+		// Only the number of matches, for shell conditionals
+		_, err := fmt.Fprintln(w, len(r.Matches))
+		return err
+	case f.format == formatJSON:
+		return writeJSON(w, r, f.timestampFormat)
+	case f.format == formatSARIF:
+		return writeSARIF(w, r)
+	case f.format == formatJUnit:
+		return writeJUnit(w, r, searches)
+	case f.format == formatTemplate:
+		return writeTemplate(w, tmpl, r)
+	case f.format == formatLine || f.format == formatNDJSON:
+		// Every match has already been written
+		return nil
+	}
+	if interactive {
+		printMatchTable(w, r.Matches, f.showTime(), f.timestampFormat, f.showID)
+	}
+	// Other formats carry the stats in their documents, or leave them out
+	if !f.noStats {
+		printStats(w, r.Stats)
+	}
+	if r.Stats.Profile != nil {
+		printProfile(w, r.Stats)
+	}
+	if r.Summary != nil {
+		printSummary(w, r.Summary)
+	}
+	if r.Diff != nil {
+		printDiff(w, r.Diff)
+	}
+	return nil
+}
+
+// writeURLResult writes the result of one URL to its file in --output-dir,
+// holding what the format would print for that URL alone
+func writeURLResult(w *os.File, f *cliFlags, r report, tmpl *template.Template, searches []string) error {
+	switch f.format {
+	case formatJSON:
+		return writeJSON(w, r, f.timestampFormat)
+	case formatSARIF:
+		return writeSARIF(w, r)
+	case formatJUnit:
+		return writeJUnit(w, r, searches)
+	case formatTemplate:
+		return writeTemplate(w, tmpl, r)
+	case formatNDJSON:
+		for _, m := range r.Matches {
+			if err := writeMatchJSON(w, m, f.timestampFormat); err != nil {
+				return err
+			}
+		}
+	case formatLine:
+		for _, m := range r.Matches {
+			if _, err := fmt.Fprintln(w, f.matchLine(m)); err != nil {
+				return err
+			}
+		}
+	default:
+		withoutColor(func() {
+			printMatchTable(w, r.Matches, f.showTime(), f.timestampFormat, f.showID)
+			if !f.noStats {
+				printStats(w, r.Stats)
+			}
+		})
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fractalized-cyber/objector"
+	"golang.org/x/term"
+)
+
+// Color modes accepted by --color
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// ANSI escape sequences used in the output, cleared when color is disabled
+var (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+	colorBold   = "\033[1m"
+	colorReset  = "\033[0m"
+)
+
+// setColor enables or disables colored output for the given mode. In auto
+// mode color is used only when stdout is a terminal understanding ANSI
+// escape sequences, as reported by ansi.
+func setColor(mode string, ansi bool) error {
+	switch mode {
+	case colorAlways:
+		return nil
+	case colorAuto:
+		if ansi && term.IsTerminal(int(os.Stdout.Fd())) {
+			return nil
+		}
+	case colorNever:
+	default:
+		return fmt.Errorf("unknown color mode %q. Use auto, always or never", mode)
+	}
+	colorRed, colorGreen, colorYellow, colorCyan, colorBold, colorReset = "", "", "", "", "", ""
+	return nil
+}
+
+// withoutColor runs f with colored output disabled, for output written to
+// files
+func withoutColor(f func()) {
+	red, green, yellow, cyan, bold, reset := colorRed, colorGreen, colorYellow, colorCyan, colorBold, colorReset
+	defer func() {
+		colorRed, colorGreen, colorYellow, colorCyan, colorBold, colorReset = red, green, yellow, cyan, bold, reset
+	}()
+	colorRed, colorGreen, colorYellow, colorCyan, colorBold, colorReset = "", "", "", "", "", ""
+	f()
+}
+
+// severityColor returns the color a severity is printed in
+func severityColor(severity objector.Severity) string {
+	switch severity {
+	case objector.SeverityCritical:
+		return colorBold + colorRed
+	case objector.SeverityHigh:
+		return colorRed
+	case objector.SeverityMedium:
+		return colorYellow
+	}
+	return colorCyan
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// diff compares the matches of a scan with a baseline result
+type diff struct {
+	Baseline   string           `json:"baseline"`
+	Key        string           `json:"key"`
+	New        []objector.Match `json:"new"`
+	Removed    []objector.Match `json:"removed"`
+	Persisting []objector.Match `json:"persisting"`
+}
+
+// loadBaseline reads the matches of a result written by --format json.
+// Timestamps are ignored, they may be in any --timestamp-format.
+func loadBaseline(path string) ([]objector.Match, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Matches []struct {
+			objector.Match
+			Timestamp json.RawMessage `json:"timestamp"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	matches := make([]objector.Match, len(doc.Matches))
+	for i, m := range doc.Matches {
+		matches[i] = m.Match
+	}
+	return matches, nil
+}
+
+// diffKey identifies a match for the comparison, by path and value or by
+// value only like the dedup modes
+func diffKey(m objector.Match, key string) string {
+	if key == objector.DedupByValue {
+		return m.Value
+	}
+	return m.Path + ":" + m.Value
+}
+
+// compareMatches splits matches into those that are new since the baseline
+// and those that persist, and lists the baseline matches that disappeared
+func compareMatches(baseline, matches []objector.Match, key string) *diff {
+	d := &diff{Key: key, New: []objector.Match{}, Removed: []objector.Match{}, Persisting: []objector.Match{}}
+	before := make(map[string]bool)
+	for _, m := range baseline {
+		before[diffKey(m, key)] = true
+	}
+	now := make(map[string]bool)
+	for _, m := range matches {
+		k := diffKey(m, key)
+		now[k] = true
+		if before[k] {
+			d.Persisting = append(d.Persisting, m)
+		} else {
+			d.New = append(d.New, m)
+		}
+	}
+	for _, m := range baseline {
+		k := diffKey(m, key)
+		if !now[k] {
+			d.Removed = append(d.Removed, m)
+			// Report a repeated baseline match once
+			now[k] = true
+		}
+	}
+	return d
+}
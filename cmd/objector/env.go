@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix starts the environment variables that stand in for flags
+const envPrefix = "OBJECTOR_"
+
+// envName is the environment variable for the flag name, e.g.
+// OBJECTOR_MAX_MATCHES for --max-matches
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnv sets every flag of fs not given on the command line from its
+// environment variable, if set, so flags take precedence over the
+// environment and the environment over the defaults. A repeatable flag
+// takes one value per line. Single letter aliases and --help have no
+// variable. It returns the number of flags set from the environment.
+func applyEnv(fs *flag.FlagSet) (int, error) {
+	// Aliases share their value, so -u given counts for --url too
+	given := make(map[flag.Value]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Value] = true
+	})
+
+	applied := 0
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || len(f.Name) == 1 || f.Name == "help" || given[f.Value] {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		values := []string{value}
+		if repeatable(f.Value) {
+			values = nil
+			for _, line := range strings.Split(value, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					values = append(values, line)
+				}
+			}
+		}
+		for _, v := range values {
+			// Set through fs, so later sources see the flag as given
+			if setErr := fs.Set(f.Name, v); setErr != nil {
+				err = fmt.Errorf("invalid %s: %w", envName(f.Name), setErr)
+				return
+			}
+		}
+		given[f.Value] = true
+		applied++
+	})
+	return applied, err
+}
+
+// repeatable reports whether the flag behind v may be given multiple times
+func repeatable(v flag.Value) bool {
+	switch v.(type) {
+	case *stringList, actionFlag:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// stringList is a flag that may be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// orUnlimited maps a flag value of 0, meaning no limit, to the negative
+// value the library uses for no limit
+func orUnlimited[T int | time.Duration](v T) T {
+	if v == 0 {
+		return -1
+	}
+	return v
+}
+
+// newLimiter builds the limiter for --rate and --delay, keeping the slower of
+// the two, or returns nil when neither is set
+func newLimiter(rate float64, delay time.Duration) *objector.Limiter {
+	interval := delay
+	if rate > 0 {
+		interval = max(interval, time.Duration(float64(time.Second)/rate))
+	}
+	if interval <= 0 {
+		return nil
+	}
+	return objector.NewLimiter(interval)
+}
+
+// headerStart matches the "Name:" that begins a header, so commas inside
+// header values are not taken as separators
+var headerStart = regexp.MustCompile("^\\s*[!#$%&'*+.^_`|~0-9A-Za-z-]+\\s*:")
+
+// parseHeaders parses the --headers list, "Name: Value,Name2: Value2". A
+// comma followed by text that does not start a header belongs to the
+// previous value, e.g. "Accept: text/html, application/json".
+func parseHeaders(list string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if strings.TrimSpace(list) == "" {
+		return headers, nil
+	}
+
+	var pairs []string
+	for _, part := range strings.Split(list, ",") {
+		if len(pairs) > 0 && !headerStart.MatchString(part) {
+			pairs[len(pairs)-1] += "," + part
+			continue
+		}
+		pairs = append(pairs, part)
+	}
+	for _, pair := range pairs {
+		name, value, err := parseHeader(pair)
+		if err != nil {
+			return nil, err
+		}
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+// loadHeaders reads a file of "Name: Value" headers, one per line as in a
+// raw HTTP header block. Blank and # lines are skipped, and so are HTTP/2
+// pseudo-headers such as ":authority", which Chrome sets itself.
+func loadHeaders(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	headers := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ":") {
+			continue
+		}
+		name, value, err := parseHeader(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		headers[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// parseHeader splits a "Name: Value" header
+func parseHeader(header string) (name, value string, err error) {
+	name, value, ok := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid header %q, expected 'Name: Value'", header)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// actionFlag adds --click and --type steps to a shared list, keeping the
+// order they were given in
+type actionFlag struct {
+	kind    string
+	actions *[]objector.Action
+}
+
+func (f actionFlag) String() string {
+	return ""
+}
+
+func (f actionFlag) Set(value string) error {
+	action := objector.Action{Kind: f.kind, Selector: value}
+	if f.kind == objector.ActionType {
+		selector, text, ok := strings.Cut(value, "=")
+		if !ok || selector == "" {
+			return fmt.Errorf("invalid action %q, expected 'selector=text'", value)
+		}
+		action.Selector, action.Text = selector, text
+	}
+	*f.actions = append(*f.actions, action)
+	return nil
+}
+
+// countFlag is an integer flag whose value may be left out, counting as 1,
+// as in --wait-for-match
+type countFlag int
+
+func (f *countFlag) String() string {
+	return strconv.Itoa(int(*f))
+}
+
+func (f *countFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*f = 1
+	case "false":
+		*f = 0
+	default:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("expected a positive number, got %q", value)
+		}
+		*f = countFlag(n)
+	}
+	return nil
+}
+
+// IsBoolFlag lets the flag be given without a value
+func (f *countFlag) IsBoolFlag() bool {
+	return true
+}
+
+// joinCounts rewrites "--name N" as "--name=N" for the count flags among
+// names, since the flag package only takes "=N" for flags whose value is
+// optional
+func joinCounts(args []string, names ...string) []string {
+	joined := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(joined, args[i:]...)
+		}
+		name := strings.TrimLeft(arg, "-")
+		if slices.Contains(names, name) && strings.HasPrefix(arg, "-") && i+1 < len(args) {
+			if _, err := strconv.Atoi(args[i+1]); err == nil {
+				arg += "=" + args[i+1]
+				i++
+			}
+		}
+		joined = append(joined, arg)
+	}
+	return joined
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want map[string]string
+	}{
+		{"empty", "", map[string]string{}},
+		{"single", "Authorization: Bearer abc", map[string]string{"Authorization": "Bearer abc"}},
+		{"colon in value", "Authorization: Basic dXNlcjpwYXNz:x", map[string]string{"Authorization": "Basic dXNlcjpwYXNz:x"}},
+		{"url value", "Referer: https://example.com:8443/a?b=c:d", map[string]string{"Referer": "https://example.com:8443/a?b=c:d"}},
+		{"several", "X-A: 1,X-B: 2", map[string]string{"X-A": "1", "X-B": "2"}},
+		{"comma in value", "Accept: text/html, application/json,X-B: 2", map[string]string{"Accept": "text/html, application/json", "X-B": "2"}},
+		{"comma before colon value", "Cookie: a=1, b=2:3", map[string]string{"Cookie": "a=1, b=2:3"}},
+		{"comma before nameless colon", "X-A: 1,: 2", map[string]string{"X-A": "1,: 2"}},
+		{"spaces trimmed", "  X-A :  1  ", map[string]string{"X-A": "1"}},
+		{"empty value", "X-Empty:", map[string]string{"X-Empty": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHeaders(tt.list)
+			if err != nil {
+				t.Fatalf("parseHeaders(%q) failed: %v", tt.list, err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseHeaders(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHeadersInvalid(t *testing.T) {
+	for _, list := range []string{"no colon", ": value", " : value,X-A: 1"} {
+		if _, err := parseHeaders(list); err == nil {
+			t.Errorf("parseHeaders(%q) succeeded, want an error", list)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		header, name, value string
+	}{
+		{"X-A: 1", "X-A", "1"},
+		{"X-Time: 12:30:00", "X-Time", "12:30:00"},
+		{"X-Url:http://h:1/", "X-Url", "http://h:1/"},
+		{"X-Unicode: café ☕", "X-Unicode", "café ☕"},
+	}
+	for _, tt := range tests {
+		name, value, err := parseHeader(tt.header)
+		if err != nil {
+			t.Errorf("parseHeader(%q) failed: %v", tt.header, err)
+			continue
+		}
+		if name != tt.name || value != tt.value {
+			t.Errorf("parseHeader(%q) = %q, %q, want %q, %q", tt.header, name, value, tt.name, tt.value)
+		}
+	}
+}
+
+func TestLoadHeaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "headers.txt")
+	content := strings.Join([]string{
+		"# exported from the browser",
+		":authority: example.com",
+		":path: /",
+		"",
+		"Authorization: Bearer a:b:c",
+		"Accept: text/html, application/json",
+		"  Cookie:  session=1; theme=dark  ",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadHeaders(path)
+	if err != nil {
+		t.Fatalf("loadHeaders failed: %v", err)
+	}
+	want := map[string]string{
+		"Authorization": "Bearer a:b:c",
+		"Accept":        "text/html, application/json",
+		"Cookie":        "session=1; theme=dark",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("loadHeaders = %v, want %v", got, want)
+	}
+}
+
+func TestLoadHeadersInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "headers.txt")
+	if err := os.WriteFile(path, []byte("X-A: 1\nnot a header\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loadHeaders(path)
+	if err == nil || !strings.Contains(err.Error(), path+":2:") {
+		t.Errorf("loadHeaders error = %v, want one naming line 2", err)
+	}
+}
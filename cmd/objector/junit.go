@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// junitTestSuites is a JUnit XML report with a test suite per URL
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+// junitProblem is the failure or error of a test case
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes r as a JUnit XML report. Each URL is a test suite with
+// a test case per search, failed by the matches of that search. A URL that
+// could not be scanned is a suite with a single errored test case.
+func writeJUnit(w io.Writer, r report, searches []string) error {
+	suites := junitTestSuites{Name: "objector", Time: fmt.Sprintf("%.3f", r.Stats.Duration.Seconds())}
+	for _, url := range r.URLs {
+		suite := junitTestSuite{Name: url}
+
+		if i := slices.IndexFunc(r.Errors, func(e scanError) bool { return e.URL == url }); i >= 0 {
+			suite.TestCases = []junitTestCase{{
+				Name:      "scan",
+				ClassName: url,
+				Error:     &junitProblem{Message: "could not scan " + url, Type: "error", Text: r.Errors[i].Error},
+			}}
+			suite.Tests, suite.Errors = 1, 1
+		} else {
+			// Matches of every search, in the order searches are listed
+			byName := make(map[string][]objector.Match)
+			names := append([]string{}, searches...)
+			for _, m := range r.Matches {
+				if m.URL != url {
+					continue
+				}
+				if !slices.Contains(names, m.Pattern) {
+					names = append(names, m.Pattern)
+				}
+				byName[m.Pattern] = append(byName[m.Pattern], m)
+			}
+			for _, name := range names {
+				testCase := junitTestCase{Name: name, ClassName: url}
+				if matches := byName[name]; len(matches) > 0 {
+					testCase.Failure = junitFailure(matches)
+					suite.Failures++
+				}
+				suite.TestCases = append(suite.TestCases, testCase)
+			}
+			suite.Tests = len(suite.TestCases)
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitFailure describes the matches of one search. The message names every
+// path and value, the text adds one line per match.
+func junitFailure(matches []objector.Match) *junitProblem {
+	severity := matches[0].Severity
+	summaries := make([]string, len(matches))
+	lines := make([]string, len(matches))
+	for i, m := range matches {
+		if m.Severity.Rank() > severity.Rank() {
+			severity = m.Severity
+		}
+		paths := m.Paths
+		if len(paths) == 0 {
+			paths = []string{m.Path}
+		}
+		summaries[i] = strings.Join(paths, ", ") + ": " + matchValue(m)
+		lines[i] = fmt.Sprintf("[%s] %s at %s: %s (id %s, seen %s)", m.Severity, m.Description, strings.Join(paths, ", "), matchValue(m), m.ID, plural(max(m.Count, 1), "time", "times"))
+	}
+	return &junitProblem{
+		Message: strings.Join(summaries, "; "),
+		Type:    string(severity),
+		Text:    strings.Join(lines, "\n"),
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// parseJUnit writes r as JUnit XML and parses it back
+func parseJUnit(t *testing.T, r report, searches []string) junitTestSuites {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := writeJUnit(&buf, r, searches); err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("JUnit output is not XML: %v\n%s", err, buf.String())
+	}
+	return suites
+}
+
+func TestWriteJUnit(t *testing.T) {
+	searches := []string{"AWS Access Key", "JWT", "Slack Token"}
+	r := report{
+		URLs: []string{"https://a.example/", "https://clean.example/", "https://down.example/"},
+		Matches: []objector.Match{
+			{ID: "1111111111111111", URL: "https://a.example/", Pattern: "JWT", Path: "window.token",
+				Value: "eyJ", Description: "JSON Web Token", Severity: objector.SeverityMedium, Count: 3},
+			{ID: "2222222222222222", URL: "https://a.example/", Pattern: "JWT", Path: "window.refresh",
+				Value: "eyK", Description: "JSON Web Token", Severity: objector.SeverityHigh, Count: 1},
+			{ID: "3333333333333333", URL: "https://a.example/", Pattern: "Custom String: hunter2", Path: "window.pw",
+				Value: "hunter2", Description: "Custom string", Severity: objector.SeverityHigh, Count: 1},
+		},
+		Errors: []scanError{{URL: "https://down.example/", Error: "net::ERR_NAME_NOT_RESOLVED"}},
+	}
+	suites := parseJUnit(t, r, searches)
+	if suites.Tests != 8 || suites.Failures != 2 || suites.Errors != 1 || len(suites.Suites) != 3 {
+		t.Fatalf("testsuites = %d tests, %d failures, %d errors in %d suites, want 8, 2, 1 in 3",
+			suites.Tests, suites.Failures, suites.Errors, len(suites.Suites))
+	}
+
+	// A test case per search, failed by its matches, then the patterns
+	// matched that are not among the searches
+	found := suites.Suites[0]
+	if found.Name != "https://a.example/" || found.Tests != 4 || found.Failures != 2 || found.Errors != 0 {
+		t.Errorf("suite %q = %d tests, %d failures, %d errors, want 4, 2, 0", found.Name, found.Tests, found.Failures, found.Errors)
+	}
+	want := []struct {
+		name   string
+		failed bool
+	}{
+		{"AWS Access Key", false},
+		{"JWT", true},
+		{"Slack Token", false},
+		{"Custom String: hunter2", true},
+	}
+	for i, w := range want {
+		if i >= len(found.TestCases) {
+			t.Errorf("missing test case %q", w.name)
+			continue
+		}
+		testCase := found.TestCases[i]
+		if testCase.Name != w.name || testCase.ClassName != found.Name || (testCase.Failure != nil) != w.failed {
+			t.Errorf("test case %d = %q in %q, failed %t, want %q, failed %t", i, testCase.Name, testCase.ClassName, testCase.Failure != nil, w.name, w.failed)
+		}
+	}
+
+	// The failure lists every match of the search, at its highest severity
+	failure := found.TestCases[1].Failure
+	if failure.Type != "high" || failure.Message != "window.token: eyJ; window.refresh: eyK" {
+		t.Errorf("failure = %q of type %q, want both matches at high", failure.Message, failure.Type)
+	}
+	lines := strings.Split(failure.Text, "\n")
+	if len(lines) != 2 || lines[0] != "[medium] JSON Web Token at window.token: eyJ (id 1111111111111111, seen 3 times)" {
+		t.Errorf("failure text = %q, want a line per match", failure.Text)
+	}
+
+	// A clean page passes every test case
+	clean := suites.Suites[1]
+	if clean.Tests != len(searches) || clean.Failures != 0 {
+		t.Errorf("clean suite = %d tests, %d failures, want %d passing", clean.Tests, clean.Failures, len(searches))
+	}
+	for _, testCase := range clean.TestCases {
+		if testCase.Failure != nil || testCase.Error != nil {
+			t.Errorf("clean test case %q failed", testCase.Name)
+		}
+	}
+
+	// A page that could not be scanned has one errored test case
+	down := suites.Suites[2]
+	if down.Tests != 1 || down.Errors != 1 || len(down.TestCases) != 1 || down.TestCases[0].Error == nil ||
+		down.TestCases[0].Error.Text != "net::ERR_NAME_NOT_RESOLVED" {
+		t.Errorf("suite of the failed URL = %+v, want one errored test case", down)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// scriptEnd matches the text that would close the <script> element wrapping
+// a --scan-js file. Escaped as <\/script it reads the same in JavaScript
+// strings and regular expressions.
+var scriptEnd = regexp.MustCompile(`(?i)</script`)
+
+// isLocal reports whether target is a file:// URL
+func isLocal(target string) bool {
+	return strings.HasPrefix(strings.ToLower(target), "file:")
+}
+
+// fileURL returns the file:// URL naming the file at path
+func fileURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// wrapScript returns a minimal page running js as a classic script, so its
+// globals are what the monitor scans
+func wrapScript(js string) string {
+	return "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"></head><body>\n<script>\n" +
+		scriptEnd.ReplaceAllStringFunc(js, func(end string) string { return `<\/` + end[2:] }) +
+		"\n</script>\n</body></html>\n"
+}
+
+// loadLocalTargets reads the --scan-file and --scan-js files, returning the
+// file:// URL naming each one and the page loaded for it
+func loadLocalTargets(htmlFiles, jsFiles []string) ([]string, map[string]string, error) {
+	var targets []string
+	contents := make(map[string]string)
+	add := func(path string, wrap func(string) string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		target, err := fileURL(path)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+		contents[target] = wrap(string(data))
+		return nil
+	}
+	for _, path := range htmlFiles {
+		if err := add(path, func(html string) string { return html }); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, path := range jsFiles {
+		if err := add(path, wrapScript); err != nil {
+			return nil, nil, err
+		}
+	}
+	return targets, contents, nil
+}
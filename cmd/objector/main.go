@@ -0,0 +1,757 @@
+// Command objector monitors the JavaScript objects of a web page for exposed
+// credentials.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/fractalized-cyber/objector"
+	"golang.org/x/term"
+)
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+func printUsage() {
+	fmt.Print(`
+OBJECTOR - JavaScript Object Monitor
+
+  A powerful tool for monitoring JavaScript objects and detecting exposed
+  credentials, API keys, and sensitive data in web applications.
+
+  USAGE:
+    objector -u <URL> [OPTIONS]
+
+  REQUIRED ARGUMENTS:
+    -u, --url <URL>              Target URL to monitor (repeat to scan several URLs), or a file:// URL
+    --scan-file <path>           Scan a local HTML file, loaded alone into a blank page (repeatable)
+    --scan-js <path>             Scan the globals a local JavaScript file leaves in a blank page (repeatable)
+    --stdin                      Read URLs from stdin, one per line, and scan each as it arrives
+
+  OPTIONAL ARGUMENTS:
+    --config <file>              JSON file with extra patterns, ignored paths, max depth and options
+    --print-config               Print the effective configuration, secrets redacted, and exit
+    --list-patterns              Print the patterns a scan would run, with their regexes and severities, and exit
+    --pattern-file <file>        JSON array or name,regex,description CSV of extra patterns (repeatable)
+    --no-default-patterns        Only monitor the patterns from --config and --pattern-file
+    --exclude-path <glob>        Skip object paths matching glob, * within a name, ** for any names (repeatable)
+    --check                      Only check that the patterns compile and the URLs respond
+    --timeout <duration>         Monitoring timeout (default: 20s)
+    --grace <duration>           Time after the timeout to record the last matches (default: 2s, 0 to stop at once)
+    --max-runtime <duration>     Stop the whole run after this long and kill it if it hangs (default: no limit)
+    --headers <headers>          Custom headers for requests
+    --headers-file <file>        File of headers for requests, one 'Name: Value' per line
+    --bearer <token>             Send 'Authorization: Bearer <token>' with requests, best set as OBJECTOR_BEARER
+    --string <custom_string>     Custom string to search for, instead of the patterns (repeatable)
+    --ignore-case                Match --string searches in any letter case
+    --string-regex <regex>       Custom regular expression to search for (repeatable)
+    --include-pattern <name>     Only run the named pattern (repeatable)
+    --exclude-pattern <name>     Do not run the named pattern (repeatable)
+    --min-severity <level>       Only report matches at least this severe: critical, high, medium, low
+    --fail-on <level>            Exit with status 3 when a match is at least this severe
+    --fail-on-match              Exit with status 3 when any match is found
+    --count-only                 Only print the number of unique matches, for shell conditionals
+    --deep-scan                  Also scan non-enumerable properties and getters
+    --decode-base64              Also scan what long base64 strings decode to
+    --once                       Scan each page once after it loads instead of monitoring it
+    --since-start                Only report matches that appear after the first scan of each page
+    --interval <duration>        Time between scan passes (default: 1s, minimum: 100ms)
+    --scan-budget <n>            Objects visited per scan pass (default: 100000, 0 for no limit)
+    --scan-time-budget <dur>     Time allowed per scan pass (default: 1s, 0 for no limit)
+    --js-match-cap <n>           Matches held in the page by a scan pass or the monitor (default: 10000, 0 for no limit)
+    --min-value-length <n>       Ignore matched values shorter than n characters
+    --max-value-length <n>       Ignore matched values longer than n characters
+    --max-value-size <bytes>     Bytes of a matched value that are reported (default: 4096, 0 for no limit)
+    --context-chars <n>          Report n characters around each match and its sibling property names
+    --verify-aws                 Check AWS keys found with a secret against AWS STS (sends them to AWS)
+    --validator <cmd>            Shell command judging each match, given as JSON on stdin: valid, invalid or unknown
+    --validator-concurrency <n>  Validator commands run at once (default: 4)
+    --validator-timeout <dur>    Time a validator command has to answer (default: 10s)
+    --dedup-by <mode>            Collapse repeat matches by path or value (default: path)
+    --emit-on-change             Report a match whenever the value at a path changes, with the previous value
+    --summary                    Print matches grouped by pattern, value and path
+    --no-stats                   Do not print the statistics after the table
+    --redact                     Only show the first and last characters of values
+    --redact-full                Replace values with a length placeholder
+    --compact-value              Collapse runs of whitespace in shown values to single spaces
+    --format <format>            Output format: table, json, ndjson, sarif, junit, line, template (default: table)
+    --output-dir <dir>           Also write the result of each URL to its own file in dir, with an index
+    --sort <order>               Order of the reported matches: severity, pattern, path, time (default: severity)
+    --template <text|name>       Go text/template for --format template, or markdown or log
+    --template-file <file>       File with the template for --format template
+    --timestamp                  Add a time column to the table
+    --timestamp-format <layout>  Go time layout, rfc3339 or unix for match timestamps
+    --show-id                    Add the short match ID to the description column of the table
+    --retries <n>                Retry failed page loads n times with backoff (default: 0)
+    --concurrency <n>            Scan up to n URLs at once, each in its own browser (default: 1)
+    --no-follow-redirects        Fail a URL that answers with an HTTP redirect instead of following it
+    --scope <file>               Only scan URLs starting with a prefix listed in file, one per line
+    --respect-robots             Skip URLs the robots.txt of their host disallows
+    --wait-for-match [n]         Monitor each page until its first match, or n matches, instead of the full timeout
+    --max-matches <n>            Stop scanning a page once it has n matches
+    --max-matches-per-pattern <n>
+                                 Keep the first n matches of each pattern, stopping once every pattern has n
+    --max-dedup-entries <n>      Remember at most n matches to skip repeats, bounding memory (default: 0, no limit)
+    --rate <n>                   At most n page loads and actions per second, across all URLs
+    --delay <duration>           Wait at least this long between page loads and actions, e.g. 2s
+    --chrome-flag <name[=value]> Extra Chrome command line flag (repeatable)
+    --override-chrome-flags      Allow --chrome-flag to change flags objector relies on
+    --insecure                   Ignore TLS certificate errors, e.g. self-signed certs (connections
+                                 are then open to interception, only use on targets you trust)
+    --proxy <url>                Proxy for all browser traffic, e.g. http://127.0.0.1:8080
+    --proxy-rules <rules>        Chrome proxy rules, e.g. "https=127.0.0.1:8080;http=direct://" (overrides --proxy)
+    --proxy-pac <url|file>       PAC file choosing a proxy per host (not combinable with --proxy)
+    --http-version <version>     HTTP version the browser speaks: auto, 1.1, 2 or 3 (default: auto)
+    --remote-chrome <url>        Use a running Chrome, e.g. ws://host:9222/devtools/browser/<id> or http://host:9222
+    --chrome-path <binary>       Chrome or Chromium executable to launch instead of the one found
+    --scan-storage               Also scan localStorage and sessionStorage entries
+    --scan-dom                   Also scan DOM attribute values, such as data-* attributes and inline handlers
+    --scan-sourcemaps            Also load the scripts' source maps and scan the original sources they embed
+    --scan-frames                Also scan same-origin iframes
+    --domains <list>             Only scan frames from these comma-separated domains
+    --color <mode>               Colored output: auto, always, never (default: auto)
+    --ascii                      Draw borders and the spinner with plain ASCII characters
+    --webhook <url>              POST each new match as JSON to this URL
+    --webhook-header <header>    Header for webhook requests, e.g. "Authorization: Bearer x" (repeatable)
+    --debug                      Log scanner and browser console diagnostics to stderr
+    --log-level <level>          Log diagnostics from this level up: debug, info, warn or error (default: error)
+    --profile                    Report the time spent launching, loading, injecting and scanning
+    --cpuprofile <file>          Write a Go CPU profile of objector itself for go tool pprof
+    --screenshot <dir>           Save a full page screenshot whenever matches are found
+    --screenshot-all             With --screenshot, also capture every page once loaded
+    --post-load-script <file>    JavaScript file to run in each page after it loads, before scanning
+    --click <selector>           Click the element after the page loads (repeatable, run in order)
+    --type <selector=text>       Type text into the element after the page loads (repeatable)
+    --strict                     Fail the scan when a --click or --type step fails
+    --diff <file>                Compare the matches with an earlier --format json result
+    --diff-key <mode>            Compare matches by path or value (default: --dedup-by)
+    --state <file>               Only report matches not seen by earlier runs using the same file
+    --baseline <file>            Leave out the accepted findings of a baseline file
+    --baseline-generate <file>   Write every finding of this run to a baseline file, as accepted
+    --har <file>                 Record all network requests and responses to a HAR file
+    --har-max-body <bytes>       Bytes of each response body kept in the HAR (default: 1048576, -1 for none)
+    --help, -h                   Show this help message
+
+  ENVIRONMENT:
+    Each flag not given on the command line is read from OBJECTOR_<FLAG>, e.g.
+    OBJECTOR_URL, OBJECTOR_TIMEOUT or OBJECTOR_HEADERS. Repeatable flags take one
+    value per line. Flags take precedence over the environment, and the environment
+    over the options of --config.
+
+  EXAMPLES:
+    objector -u [url]
+    objector -u [url] --timeout 30s
+    objector -u [url] --headers "Authorization: Bearer token"
+    objector -u [url] --string "my-secret-key"
+    objector -u [url] --string "staging-key" --string-regex "tok_[0-9a-f]{32}"
+    objector -u [url] --format json --redact
+    objector -u [url] --include-pattern "AWS Access Key" --include-pattern "AWS Secret Key"
+    objector -u [url] -u [url2] --retries 3
+    objector -u [url] --config patterns.json --check
+    objector -u [url] --click "#settings" --type "#search=api key"
+    objector -u [url] --format template --template markdown
+    objector -u [url] --once --format sarif --fail-on high
+    objector -u [url] --once --state objector.state
+    objector -u [url] --once --format json --diff baseline.json
+    objector -u [url] --chrome-flag lang=de-DE --chrome-flag disable-web-security
+
+  DETECTED PATTERNS:
+    • AWS Access Keys (AKIA format)
+    • AWS Secret Keys (40-character base64, standalone or assigned to a secret key)
+    • Private Keys (RSA, DSA, EC, OpenSSH, PGP)
+    • JWT Tokens (eyJ format)
+    • GitHub Tokens (ghp_, gho_, ghu_, ghs_, ghr_, github_pat_)
+    • GitLab Personal Access Tokens (glpat-)
+    • Slack Tokens (xoxb-, xoxa-, xoxp-, xoxr-, xoxs-)
+    • Stripe Live Keys (sk_live_, rk_live_, pk_live_)
+    • Google API Keys (AIza format)
+    • Twilio Account and API Key SIDs
+
+`)
+}
+
+func main() {
+	// Custom usage function
+	flag.Usage = printUsage
+
+	f, err := parseFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Windows consoles need escape sequences turned on, and those too old
+	// for them get plain output
+	ansi := enableVirtualTerminal()
+	if err := setColor(f.colorMode, ansi); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if f.asciiOutput || !ansi {
+		setASCII()
+	}
+
+	// Check if help is requested
+	if f.help || f.helpShort {
+		printUsage()
+		os.Exit(0)
+	}
+
+	// Check if no arguments provided
+	if len(os.Args) == 1 && f.fromEnv == 0 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if err := validateFlags(f); err != nil {
+		fmt.Printf(colorRed+"Error: %v."+colorReset+"\n", err)
+		os.Exit(1)
+	}
+
+	// Given to --config, the printed configuration runs the same scan once
+	// the redacted secrets are filled back in
+	if f.printConfig {
+		f.config.Options = effectiveOptions(flag.CommandLine)
+		if f.config.IgnoredPaths == nil {
+			f.config.IgnoredPaths = []string{}
+		}
+		data, err := json.MarshalIndent(f.config, "", "  ")
+		if err != nil {
+			fmt.Printf(colorRed+"Error: could not print config: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		os.Exit(0)
+	}
+
+	// The patterns are listed as selected for a scan, no URL is needed
+	if f.listPatterns {
+		minimum, _ := parseSeverityFlag(f.minSeverity)
+		patterns, unknown, err := objector.ActivePatterns(objector.Options{
+			Patterns:          f.config.Patterns,
+			NoDefaultPatterns: f.noDefaultPatterns,
+			IncludePatterns:   f.includePatterns,
+			ExcludePatterns:   f.excludePatterns,
+			MinSeverity:       minimum,
+			CustomStrings:     f.customStrings,
+			IgnoreCase:        f.ignoreCase,
+			CustomRegexes:     f.customRegexes,
+		})
+		if err != nil {
+			fmt.Printf(colorRed+"Error: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+		for _, name := range unknown {
+			fmt.Fprintf(os.Stderr, colorYellow+"Warning: unknown pattern %q"+colorReset+"\n", name)
+		}
+		printPatternTable(os.Stdout, patterns)
+		fmt.Printf("%s active\n", plural(len(patterns), "pattern", "patterns"))
+		os.Exit(0)
+	}
+
+	// Diagnostics go to stderr, keeping stdout for the results
+	var level slog.Level
+	level.UnmarshalText([]byte(f.logLevel))
+	if f.debug {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	localTargets, contents, err := loadLocalTargets(f.scanFiles, f.scanScripts)
+	if err != nil {
+		fmt.Printf(colorRed+"Error: could not read file to scan: %v"+colorReset+"\n", err)
+		os.Exit(1)
+	}
+	targets := append(f.targets, localTargets...)
+	// Nobody is going to type the URLs in
+	if f.readStdin && term.IsTerminal(int(os.Stdin.Fd())) {
+		printUsage()
+		os.Exit(1)
+	}
+	if len(targets) == 0 && !f.readStdin {
+		fmt.Println(colorRed + "Error: URL is required. Use -u or --url to specify the target URL, or --scan-file or --scan-js for a local file." + colorReset)
+		fmt.Println("Run 'objector --help' for usage information.")
+		os.Exit(1)
+	}
+	if f.remoteChrome != "" && (len(f.chromeFlags) > 0 || f.chromePath != "" || f.insecure || f.proxy != "" || f.proxyRules != "" || f.proxyPAC != "" || f.httpVersion != "auto") {
+		fmt.Fprintln(os.Stderr, colorYellow+"Warning: --chrome-flag, --chrome-path, --insecure, --http-version and the proxy options only apply to a browser objector launches, and are ignored with --remote-chrome"+colorReset)
+	}
+
+	// Values are redacted as they are rendered, in every format
+	failSeverity := f.failSeverity()
+	redactValue := f.redactor()
+
+	// Load the result to compare with
+	var baseline []objector.Match
+	if f.diffBaseline != "" {
+		if baseline, err = loadBaseline(f.diffBaseline); err != nil {
+			fmt.Printf(colorRed+"Error: could not load baseline: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Only scan the targets the rules of engagement allow
+	var scope []*url.URL
+	var rules *robots
+	if f.scopeFile != "" {
+		if scope, err = loadScope(f.scopeFile); err != nil {
+			fmt.Printf(colorRed+"Error: could not load scope: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+	}
+	if f.respectRobots {
+		rules = newRobots()
+	}
+	if (scope != nil || rules != nil) && len(targets) > 0 {
+		if targets = filterTargets(targets, scope, rules); len(targets) == 0 && !f.readStdin {
+			fmt.Println(colorRed + "Error: no URL is in scope." + colorReset)
+			os.Exit(1)
+		}
+	}
+
+	// The spinner and the fitted table are only used on a terminal, the
+	// spinner only if it can erase its line
+	interactive := term.IsTerminal(int(os.Stdout.Fd()))
+	spinning := interactive && ansi
+	spinnerIndex := 0
+
+	// Position in the target list and the matches of earlier targets, for
+	// the spinner, of the latest URL started when several are scanned at
+	// once
+	targetIndex, earlierMatches := 0, 0
+
+	// Function to print the spinner with the progress of the current page
+	printSpinner := func(stats objector.Stats) {
+		if !spinning {
+			return
+		}
+		status := fmt.Sprintf("%s objects, %s", formatCount(stats.ObjectsScanned),
+			plural(earlierMatches+stats.MatchesFound, "match", "matches"))
+		if !f.once {
+			left := max(f.timeout-stats.Duration, 0).Round(time.Second)
+			status += fmt.Sprintf(", %s left", left)
+		}
+		if len(targets) > 1 {
+			status = fmt.Sprintf("[%d/%d] %s", targetIndex+1, len(targets), status)
+		}
+		fmt.Printf("\r\033[K%s Scanning... %s", spinnerFrames[spinnerIndex], status)
+		spinnerIndex = (spinnerIndex + 1) % len(spinnerFrames)
+	}
+
+	// Clear the spinner line
+	clearSpinner := func() {
+		if !spinning {
+			return
+		}
+		fmt.Print("\r\033[K")
+	}
+
+	// Parse the output template up front, so mistakes surface before scanning
+	var outputTemplate *template.Template
+	if f.format == formatTemplate {
+		if outputTemplate, err = parseTemplate(f.templateFile, f.templateText, f.timestampFormat); err != nil {
+			fmt.Printf(colorRed+"Error: invalid template: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+	}
+
+	scanOpts, err := buildOptions(f)
+	if err != nil {
+		fmt.Printf(colorRed+"Error: %v."+colorReset+"\n", err)
+		os.Exit(1)
+	}
+
+	// The searches the scan runs, each a test case of a JUnit report,
+	// warning about pattern names that would silently select nothing
+	active, unknown, err := objector.ActivePatterns(scanOpts)
+	if err != nil {
+		fmt.Printf(colorRed+"Error: %v"+colorReset+"\n", err)
+		os.Exit(1)
+	}
+	var searches []string
+	for _, p := range active {
+		searches = append(searches, p.Name)
+	}
+	if len(unknown) > 0 {
+		all, _, err := objector.ActivePatterns(objector.Options{Patterns: f.config.Patterns, NoDefaultPatterns: f.noDefaultPatterns})
+		if err != nil {
+			fmt.Printf(colorRed+"Error: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+		var known []string
+		for _, p := range all {
+			known = append(known, p.Name)
+		}
+		for _, name := range unknown {
+			fmt.Fprintf(os.Stderr, colorYellow+"Warning: unknown pattern %q"+colorReset+"\n", name)
+		}
+		fmt.Fprintf(os.Stderr, "Known patterns: %s\n", strings.Join(known, ", "))
+	}
+
+	// URLs from stdin are read as the scan goes, so results flow while the
+	// tool upstream is still writing them. A check needs them all upfront.
+	var stdinTargets <-chan string
+	if f.readStdin {
+		stdinTargets = readTargets(os.Stdin)
+		if f.check {
+			for target := range stdinTargets {
+				targets = append(targets, filterTargets([]string{target}, scope, rules)...)
+			}
+		}
+	}
+
+	// Validate the setup without scanning
+	if f.check {
+		if !runCheck(os.Stdout, targets, scanOpts) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// On a terminal the table is laid out to fit once every match is in,
+	// otherwise it is streamed with fixed column widths while scanning
+	if f.format == formatTable {
+		if !interactive && !f.countOnly {
+			timeWidth := 0
+			if f.showTime() {
+				timeWidth = len(formatTimestamp(time.Now(), f.timestampFormat))
+			}
+			layout := fixedTableLayout(timeWidth)
+			printTableHeader(os.Stdout, layout)
+			scanOpts.OnMatch = func(match objector.Match) {
+				clearSpinner()
+				match = redactMatch(match, redactValue)
+				printTableRow(os.Stdout, layout, matchRow(match, f.timestampFormat, f.showID))
+			}
+		}
+		scanOpts.OnScan = printSpinner
+	}
+
+	// Lines are written as matches are found, for shell pipelines
+	if f.format == formatLine {
+		scanOpts.OnMatch = func(match objector.Match) {
+			match = redactMatch(match, redactValue)
+			fmt.Println(f.matchLine(match))
+		}
+	}
+
+	// NDJSON writes a match per line as it is found. Matches are only kept
+	// when the summary or diff need them, so memory does not grow with the
+	// length of the scan.
+	failMatched := false
+	if f.format == formatNDJSON {
+		scanOpts.StreamMatches = !f.showSummary && f.diffBaseline == "" && f.baselineGenerate == "" && f.outputDir == ""
+		scanOpts.OnMatch = func(match objector.Match) {
+			if failSeverity != "" && match.Severity.AtLeast(failSeverity) {
+				failMatched = true
+			}
+			match = redactMatch(match, redactValue)
+			if err := writeMatchJSON(os.Stdout, match, f.timestampFormat); err != nil {
+				fmt.Fprintf(os.Stderr, colorRed+"Error: could not write output: %v"+colorReset+"\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Push matches to the webhook as they are found
+	var hook *webhook
+	if f.webhookURL != "" {
+		hookHeaders := make(map[string]string)
+		for _, header := range f.webhookHeaders {
+			name, value, err := parseHeader(header)
+			if err != nil {
+				fmt.Printf(colorRed+"Error: %v"+colorReset+"\n", err)
+				os.Exit(1)
+			}
+			hookHeaders[name] = value
+		}
+		var err error
+		if hook, err = newWebhook(f.webhookURL, hookHeaders); err != nil {
+			fmt.Printf(colorRed+"Error: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+		onMatch := scanOpts.OnMatch
+		scanOpts.OnMatch = func(match objector.Match) {
+			if onMatch != nil {
+				onMatch(match)
+			}
+			match = redactMatch(match, redactValue)
+			hook.send(match)
+		}
+	}
+
+	// Verification is the only place objector sends a finding anywhere but
+	// its own output, so say so every time
+	var verifier *awsVerifier
+	if f.verifyAWS {
+		verifier = newAWSVerifier()
+		fmt.Fprintln(os.Stderr, colorYellow+"Note: --verify-aws sends the AWS keys found to "+stsEndpoint+" to check them"+colorReset)
+	}
+
+	// Matches carry the verdicts of --verify-aws and --validator only once
+	// the scan of their URL is done, so they are written and sent then
+	var writeAnnotated func(objector.Match)
+	if verifier != nil || f.validatorCommand != "" {
+		writeAnnotated, scanOpts.OnMatch = scanOpts.OnMatch, nil
+		scanOpts.StreamMatches = false
+	}
+
+	// Scans run at once share the output and the results, guarded by mu
+	var mu sync.Mutex
+	if onMatch := scanOpts.OnMatch; onMatch != nil {
+		scanOpts.OnMatch = func(match objector.Match) {
+			mu.Lock()
+			defer mu.Unlock()
+			onMatch(match)
+		}
+	}
+	if onScan := scanOpts.OnScan; onScan != nil {
+		scanOpts.OnScan = func(stats objector.Stats) {
+			mu.Lock()
+			defer mu.Unlock()
+			onScan(stats)
+		}
+	}
+
+	// Profile the CPU time of objector itself, the browser runs in its own
+	// processes
+	if f.cpuProfile != "" {
+		file, err := os.Create(f.cpuProfile)
+		if err != nil {
+			fmt.Printf(colorRed+"Error: could not create CPU profile: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		if err := pprof.StartCPUProfile(file); err != nil {
+			fmt.Printf(colorRed+"Error: could not start CPU profile: %v"+colorReset+"\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Cancel the scan cleanly on interruption so partial results are still
+	// reported. A second signal kills the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	if f.maxRuntime > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		startWatchdog(f.maxRuntime, cancel)
+	}
+
+	// nextTarget returns the i-th target, waiting for it on stdin if needed
+	nextTarget := func(i int) (string, bool) {
+		for i >= len(targets) {
+			if stdinTargets == nil {
+				return "", false
+			}
+			select {
+			case target, ok := <-stdinTargets:
+				if !ok {
+					return "", false
+				}
+				mu.Lock()
+				targets = append(targets, filterTargets([]string{target}, scope, rules)...)
+				mu.Unlock()
+			case <-ctx.Done():
+				return "", false
+			}
+		}
+		return targets[i], true
+	}
+
+	// Scan the targets, up to --concurrency at once, recording failures and
+	// moving on. Without a browser no other URL can be scanned either, so
+	// the scans not started yet are cancelled.
+	var result report
+	// Statistics of each URL, for --output-dir
+	urlStats := make(map[string]objector.Stats)
+	scanCtx, cancelScans := context.WithCancel(ctx)
+	defer cancelScans()
+	chromeMissing := false
+	scanTargets(scanCtx, f.concurrency, nextTarget, func(i int, targetURL string) {
+		mu.Lock()
+		targetIndex, earlierMatches = i, len(result.Matches)
+		mu.Unlock()
+		opts := scanOpts
+		opts.Content = contents[targetURL]
+		matches, stats, err := objector.Scan(scanCtx, targetURL, opts)
+		// Scans cancelled for want of a browser are not reported
+		if scanCtx.Err() != nil && ctx.Err() == nil {
+			return
+		}
+		if verifier != nil {
+			mu.Lock()
+			clearSpinner()
+			mu.Unlock()
+			verifier.verifyMatches(matches)
+		}
+		if f.validatorCommand != "" && ctx.Err() == nil {
+			mu.Lock()
+			clearSpinner()
+			mu.Unlock()
+			validator{command: f.validatorCommand, concurrency: f.validatorConcurrency, timeout: f.validatorTimeout}.annotate(ctx, matches)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if writeAnnotated != nil {
+			for _, m := range matches {
+				writeAnnotated(m)
+			}
+		}
+		result.Matches = append(result.Matches, matches...)
+		addStats(&result.Stats, stats)
+		if f.outputDir != "" {
+			total := urlStats[targetURL]
+			addStats(&total, stats)
+			urlStats[targetURL] = total
+		}
+
+		if stats.FinalURL != "" && stats.FinalURL != targetURL {
+			result.Redirects = append(result.Redirects, redirect{URL: targetURL, FinalURL: stats.FinalURL})
+			if f.format == formatTable || f.format == formatLine || f.format == formatNDJSON {
+				clearSpinner()
+				fmt.Fprintf(os.Stderr, colorYellow+"Note: %s redirected to %s, which was scanned instead"+colorReset+"\n", targetURL, stats.FinalURL)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, objector.ErrChromeNotFound) {
+			if chromeMissing {
+				return
+			}
+			chromeMissing = true
+			cancelScans()
+			result.Errors = append(result.Errors, scanError{URL: targetURL, Error: err.Error()})
+			clearSpinner()
+			fmt.Fprintf(os.Stderr, colorRed+"Error: could not launch Chrome: %v"+colorReset+"\n", err)
+			fmt.Fprintln(os.Stderr, colorYellow+"Hint: install Google Chrome or Chromium (https://www.google.com/chrome/), point --chrome-path at its executable, or use a running browser with --remote-chrome"+colorReset)
+			return
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, scanError{URL: targetURL, Error: err.Error()})
+			if f.format == formatTable || f.format == formatLine || f.format == formatNDJSON {
+				clearSpinner()
+				fmt.Fprintf(os.Stderr, colorRed+"Error: could not scan %s: %v"+colorReset+"\n", targetURL, err)
+				if errors.Is(err, objector.ErrCertificate) {
+					fmt.Fprintf(os.Stderr, colorYellow+"Hint: rerun with --insecure to ignore certificate errors, e.g. for a self-signed certificate"+colorReset+"\n")
+				}
+			}
+		}
+	})
+
+	if len(targets) == 0 && ctx.Err() == nil {
+		fmt.Println(colorRed + "Error: no URL to scan was read from stdin." + colorReset)
+		os.Exit(1)
+	}
+	result.URLs = targets
+
+	// Finish delivering before reporting
+	if hook != nil {
+		clearSpinner()
+		hook.close()
+	}
+
+	// Write the traffic and matches recorded so far, even when interrupted
+	saveFailed := false
+	if scanOpts.HAR != nil {
+		if err := scanOpts.HAR.WriteFile(f.harFile); err != nil {
+			clearSpinner()
+			fmt.Fprintf(os.Stderr, colorRed+"Error: could not write HAR: %v"+colorReset+"\n", err)
+			saveFailed = true
+		}
+	}
+	if scanOpts.State != nil {
+		if err := scanOpts.State.Save(f.stateFile); err != nil {
+			clearSpinner()
+			fmt.Fprintf(os.Stderr, colorRed+"Error: could not save state: %v"+colorReset+"\n", err)
+			saveFailed = true
+		}
+	}
+	if f.baselineGenerate != "" {
+		generated := scanOpts.Baseline
+		if generated == nil {
+			generated = objector.NewBaseline()
+		}
+		for _, m := range result.Matches {
+			generated.Add(m)
+		}
+		clearSpinner()
+		if err := generated.Save(f.baselineGenerate); err != nil {
+			fmt.Fprintf(os.Stderr, colorRed+"Error: could not save baseline: %v"+colorReset+"\n", err)
+			saveFailed = true
+		} else {
+			fmt.Fprintf(os.Stderr, colorYellow+"Note: wrote %s to %s"+colorReset+"\n", plural(generated.Len(), "accepted finding", "accepted findings"), f.baselineGenerate)
+		}
+	}
+
+	if f.showSummary {
+		result.Summary = summarize(result.Matches)
+		for i := range result.Summary.ByValue {
+			result.Summary.ByValue[i].Name = redactValue(result.Summary.ByValue[i].Name)
+		}
+	}
+	if f.diffBaseline != "" {
+		result.Diff = compareMatches(baseline, result.Matches, f.diffKey())
+		result.Diff.Baseline = f.diffBaseline
+		result.Diff.New = redactMatches(result.Diff.New, redactValue)
+		result.Diff.Removed = redactMatches(result.Diff.Removed, redactValue)
+		result.Diff.Persisting = redactMatches(result.Diff.Persisting, redactValue)
+	}
+	result.Matches = redactMatches(result.Matches, redactValue)
+	sortMatches(result.Matches, f.sortOrder)
+
+	// Each file holds what the format would print for its URL alone
+	if f.outputDir != "" {
+		writeFile := func(file *os.File, r report) error {
+			return writeURLResult(file, f, r, outputTemplate, searches)
+		}
+		if err := writeOutputDir(f.outputDir, result, urlStats, outputExtensions[f.format], writeFile); err != nil {
+			clearSpinner()
+			fmt.Fprintf(os.Stderr, colorRed+"Error: could not write --output-dir: %v"+colorReset+"\n", err)
+			saveFailed = true
+		}
+	}
+
+	// Clear the spinner before showing the result
+	if f.format == formatTable {
+		clearSpinner()
+	}
+	if err := writeResult(os.Stdout, f, result, outputTemplate, searches, interactive); err != nil {
+		fmt.Fprintf(os.Stderr, colorRed+"Error: could not write output: %v"+colorReset+"\n", err)
+		os.Exit(1)
+	}
+
+	// JSON carries the profile in its stats, other formats leave stdout
+	// to their own output
+	if result.Stats.Profile != nil && (f.countOnly || (f.format != formatTable && f.format != formatJSON)) {
+		printProfile(os.Stderr, result.Stats)
+	}
+	// Exiting skips deferred calls, so the profile is completed here
+	if f.cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+
+	os.Exit(f.exitStatus(context.Cause(ctx), result, failMatched, saveFailed))
+}
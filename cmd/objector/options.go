@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// redactedOption replaces the secret parts of options printed by
+// --print-config
+const redactedOption = "[REDACTED]"
+
+// unsetOptions are the flags a config file cannot set
+var unsetOptions = map[string]bool{"config": true, "print-config": true, "list-patterns": true, "help": true, "h": true}
+
+// secretOptions redact the values of flags that may hold credentials
+var secretOptions = map[string]func(string) string{
+	"headers":        redactHeaders,
+	"webhook-header": redactHeaders,
+	"bearer":         redactAll,
+	"proxy":          redactURL,
+	"webhook":        redactURL,
+	"type":           redactTyped,
+}
+
+// applyConfig sets every flag of fs named in options that was given neither
+// on the command line nor in the environment. A repeatable flag takes a
+// list; strings, numbers and booleans are given to the flag as text.
+func applyConfig(fs *flag.FlagSet, options map[string]interface{}) error {
+	// Aliases share their value, so -u given counts for --url too
+	given := make(map[flag.Value]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Value] = true
+	})
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || unsetOptions[name] {
+			return fmt.Errorf("unknown option %q", name)
+		}
+		if given[f.Value] {
+			continue
+		}
+		values, err := optionValues(options[name])
+		if err != nil {
+			return fmt.Errorf("option %q: %w", name, err)
+		}
+		if len(values) != 1 && !repeatable(f.Value) {
+			return fmt.Errorf("option %q takes a single value", name)
+		}
+		for _, value := range values {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("option %q: %w", name, err)
+			}
+		}
+		given[f.Value] = true
+	}
+	return nil
+}
+
+// optionValues returns the flag values of a JSON option value
+func optionValues(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		switch v := item.(type) {
+		case string:
+			values = append(values, v)
+		case bool:
+			values = append(values, strconv.FormatBool(v))
+		case float64:
+			values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			return nil, fmt.Errorf("must be a string, number, boolean or a list of them")
+		}
+	}
+	return values, nil
+}
+
+// effectiveOptions returns the options of a config file setting the flags
+// set in fs, from the command line, the environment or the config, under
+// their long names. Secrets are redacted, so the options only reproduce the
+// run once they are filled back in.
+func effectiveOptions(fs *flag.FlagSet) map[string]interface{} {
+	names := make(map[flag.Value]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) > len(names[f.Value]) {
+			names[f.Value] = f.Name
+		}
+	})
+
+	options := make(map[string]interface{})
+	fs.Visit(func(f *flag.Flag) {
+		name := names[f.Value]
+		// The printed config holds the patterns of the pattern files
+		if unsetOptions[name] || name == "pattern-file" {
+			return
+		}
+		redact := secretOptions[name]
+		if redact == nil {
+			redact = func(value string) string { return value }
+		}
+		switch v := f.Value.(type) {
+		case *stringList:
+			values := make([]string, len(*v))
+			for i, value := range *v {
+				values[i] = redact(value)
+			}
+			options[name] = values
+		case actionFlag:
+			var values []string
+			for _, action := range *v.actions {
+				switch {
+				case action.Kind != v.kind:
+				case action.Kind == objector.ActionType:
+					values = append(values, redact(action.Selector+"="+action.Text))
+				default:
+					values = append(values, action.Selector)
+				}
+			}
+			options[name] = values
+		case *countFlag:
+			// A count is a boolean flag to the flag package, but its value
+			// is the number
+			options[name] = int(*v)
+		default:
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+				options[name] = f.Value.String() == "true"
+			} else {
+				options[name] = redact(f.Value.String())
+			}
+		}
+	})
+	return options
+}
+
+// redactAll hides the whole value, such as a token
+func redactAll(string) string {
+	return redactedOption
+}
+
+// redactHeaders keeps only the names of a list of headers
+func redactHeaders(list string) string {
+	headers, err := parseHeaders(list)
+	if err != nil {
+		return redactedOption
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name+": "+redactedOption)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// redactURL keeps the scheme and host of a URL, hiding its credentials and
+// its path and query, where webhooks often carry a token
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return redactedOption
+	}
+	redacted := u.Scheme + "://"
+	if u.User != nil {
+		redacted += redactedOption + "@"
+	}
+	redacted += u.Host
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		redacted += "/" + redactedOption
+	}
+	return redacted
+}
+
+// redactTyped keeps the selector of a --type step, hiding the text typed,
+// such as a password
+func redactTyped(value string) string {
+	selector, _, _ := strings.Cut(value, "=")
+	return selector + "=" + redactedOption
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEffectiveOptionsRoundTrip(t *testing.T) {
+	args := []string{"-u", "https://a.example", "--url", "https://b.example", "--timeout", "30s",
+		"--wait-for-match", "3", "--string", "alpha", "--string", "beta", "--click", "#go",
+		"--once", "--format", "json", "--rate", "2.5"}
+	fs := flag.NewFlagSet("objector", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	f, err := parseFlags(fs, args)
+	if err != nil {
+		t.Fatalf("parseFlags(%q) failed: %v", args, err)
+	}
+	printed := effectiveOptions(fs)
+	if printed["wait-for-match"] != 3 {
+		t.Errorf("wait-for-match = %v, want the count 3", printed["wait-for-match"])
+	}
+
+	// Fed back through --config, the printed options set the same flags
+	data, err := json.Marshal(map[string]interface{}{"options": printed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	replayed := flag.NewFlagSet("objector", flag.ContinueOnError)
+	replayed.SetOutput(io.Discard)
+	g, err := parseFlags(replayed, []string{"--config", path})
+	if err != nil {
+		t.Fatalf("parseFlags with the printed config failed: %v", err)
+	}
+	if got, want := mustJSON(t, effectiveOptions(replayed)), mustJSON(t, printed); got != want {
+		t.Errorf("options after the round trip = %s, want %s", got, want)
+	}
+	if g.waitForMatch != f.waitForMatch || g.timeout != 30*time.Second || !g.once || g.rate != 2.5 {
+		t.Errorf("flags after the round trip = %d, %s, %t, %g, want those given", g.waitForMatch, g.timeout, g.once, g.rate)
+	}
+	if len(g.targets) != 2 || len(g.customStrings) != 2 || len(g.actions) != 1 {
+		t.Errorf("lists after the round trip = %q, %q, %v, want those given", g.targets, g.customStrings, g.actions)
+	}
+}
+
+func TestEffectiveOptionsRedacts(t *testing.T) {
+	fs := flag.NewFlagSet("objector", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err := parseFlags(fs, []string{"--headers", "Authorization: Bearer x", "--type", "#pass=hunter2",
+		"--webhook", "https://hooks.example/T0/secret", "--bearer", "token"}); err != nil {
+		t.Fatal(err)
+	}
+	options := effectiveOptions(fs)
+	if got := options["headers"]; got != "Authorization: [REDACTED]" {
+		t.Errorf("headers = %v, want the value redacted", got)
+	}
+	if got := options["type"].([]string); len(got) != 1 || got[0] != "#pass=[REDACTED]" {
+		t.Errorf("type = %v, want the text redacted", got)
+	}
+	if got := options["webhook"]; got != "https://hooks.example/[REDACTED]" {
+		t.Errorf("webhook = %v, want the path redacted", got)
+	}
+	if got := options["bearer"]; got != "[REDACTED]" {
+		t.Errorf("bearer = %v, want the token redacted", got)
+	}
+}
+
+// mustJSON returns v as JSON, for comparing values decoded differently
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
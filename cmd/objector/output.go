@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// Output formats
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatSARIF    = "sarif"
+	formatJUnit    = "junit"
+	formatLine     = "line"
+	formatNDJSON   = "ndjson"
+	formatTemplate = "template"
+)
+
+// formats lists every value accepted by --format
+var formats = []string{formatTable, formatJSON, formatSARIF, formatJUnit, formatLine, formatNDJSON, formatTemplate}
+
+// Match orders accepted by --sort
+const (
+	sortSeverity = "severity"
+	sortPattern  = "pattern"
+	sortPath     = "path"
+	sortTime     = "time"
+)
+
+// sortOrders lists every value accepted by --sort
+var sortOrders = []string{sortSeverity, sortPattern, sortPath, sortTime}
+
+// sortMatches orders matches in place. Ties keep the order the matches were
+// found in, so sorting by time leaves them as they are.
+func sortMatches(matches []objector.Match, order string) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch order {
+		case sortSeverity:
+			return a.Severity.Rank() > b.Severity.Rank()
+		case sortPattern:
+			return a.Pattern < b.Pattern
+		case sortPath:
+			return a.Path < b.Path
+		}
+		return false
+	})
+}
+
+// lineEscaper keeps each field of a line on one line and free of the
+// separator
+var lineEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// formatMatchLine renders a match for --format line as tab-separated
+// pattern, path, value, description, severity, captured group, which is
+// empty for patterns without one, ID and count
+func formatMatchLine(m objector.Match) string {
+	fields := []string{m.Pattern, m.Path, m.Value, m.Description, string(m.Severity), m.Captured, m.ID, strconv.Itoa(m.Count)}
+	for i, field := range fields {
+		fields[i] = lineEscaper.Replace(field)
+	}
+	return strings.Join(fields, "\t")
+}
+
+// report is the document written by --format json and rendered by --format
+// template
+type report struct {
+	URLs      []string         `json:"urls"`
+	Matches   []objector.Match `json:"matches"`
+	Stats     objector.Stats   `json:"stats"`
+	Errors    []scanError      `json:"errors,omitempty"`
+	Redirects []redirect       `json:"redirects,omitempty"`
+	Summary   *summary         `json:"summary,omitempty"`
+	Diff      *diff            `json:"diff,omitempty"`
+}
+
+// scanError records a URL that could not be scanned
+type scanError struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// redirect records a URL that was scanned at the URL it redirected to
+type redirect struct {
+	URL      string `json:"url"`
+	FinalURL string `json:"finalUrl"`
+}
+
+// addStats accumulates the statistics of one scanned URL into total
+func addStats(total *objector.Stats, stats objector.Stats) {
+	total.ObjectsScanned += stats.ObjectsScanned
+	total.MatchesFound += stats.MatchesFound
+	total.ExistingMatches += stats.ExistingMatches
+	total.PagesScanned += stats.PagesScanned
+	total.TruncatedScans += stats.TruncatedScans
+	total.CappedScans += stats.CappedScans
+	total.StoppedEarly += stats.StoppedEarly
+	total.Duration += stats.Duration
+	total.Screenshots = append(total.Screenshots, stats.Screenshots...)
+	if stats.Profile != nil {
+		if total.Profile == nil {
+			total.Profile = &objector.Profile{}
+		}
+		total.Profile.Launch += stats.Profile.Launch
+		total.Profile.Navigation += stats.Profile.Navigation
+		total.Profile.Setup += stats.Profile.Setup
+		total.Profile.Injection += stats.Profile.Injection
+		total.Profile.Scanning += stats.Profile.Scanning
+		total.Profile.Passes = append(total.Profile.Passes, stats.Profile.Passes...)
+	}
+	for _, name := range stats.FailedInterceptors {
+		if !slices.Contains(total.FailedInterceptors, name) {
+			total.FailedInterceptors = append(total.FailedInterceptors, name)
+		}
+	}
+	for _, name := range stats.DisabledPatterns {
+		if !slices.Contains(total.DisabledPatterns, name) {
+			total.DisabledPatterns = append(total.DisabledPatterns, name)
+		}
+	}
+}
+
+// jsonMatch is a match whose timestamp is encoded in a custom format
+type jsonMatch struct {
+	objector.Match
+	Timestamp interface{} `json:"timestamp"`
+	LastSeen  interface{} `json:"lastSeen,omitempty"`
+}
+
+// newJSONMatch encodes the times of m in format
+func newJSONMatch(m objector.Match, format string) jsonMatch {
+	jm := jsonMatch{Match: m, Timestamp: timestampJSON(m.Timestamp, format)}
+	if m.LastSeen != nil {
+		jm.LastSeen = timestampJSON(*m.LastSeen, format)
+	}
+	return jm
+}
+
+// writeMatchJSON writes m as one line of JSON, the same object as in
+// writeJSON
+func writeMatchJSON(w io.Writer, m objector.Match, timestampFormat string) error {
+	var doc interface{} = m
+	if timestampFormat != "" {
+		doc = newJSONMatch(m, timestampFormat)
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
+
+// writeJSON writes r as an indented document. Timestamps are RFC 3339 unless
+// timestampFormat is set.
+func writeJSON(w io.Writer, r report, timestampFormat string) error {
+	if r.Matches == nil {
+		r.Matches = []objector.Match{}
+	}
+	var doc interface{} = r
+	if timestampFormat != "" {
+		matches := make([]jsonMatch, len(r.Matches))
+		for i, m := range r.Matches {
+			matches[i] = newJSONMatch(m, timestampFormat)
+		}
+		doc = struct {
+			report
+			Matches []jsonMatch `json:"matches"`
+		}{r, matches}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
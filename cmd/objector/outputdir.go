@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// indexFileName is the name of the index --output-dir writes next to the
+// result files, without its extension
+const indexFileName = "index"
+
+// maxFileNameLength caps the length of a result file name before its
+// suffix and extension
+const maxFileNameLength = 100
+
+// outputExtensions is the extension of a result file in each format
+var outputExtensions = map[string]string{
+	formatTable:    ".txt",
+	formatJSON:     ".json",
+	formatSARIF:    ".sarif",
+	formatJUnit:    ".xml",
+	formatLine:     ".tsv",
+	formatNDJSON:   ".ndjson",
+	formatTemplate: ".txt",
+}
+
+var (
+	// unsafeFileChars are the runs of characters left out of file names
+	unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	// reservedFileNames are the device names Windows does not allow as
+	// files, with any extension
+	reservedFileNames = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[0-9]|lpt[0-9])(\.|$)`)
+)
+
+// outputIndex is the index of the result files in --output-dir
+type outputIndex struct {
+	Files []outputFile `json:"files"`
+}
+
+// outputFile describes the result file of one URL
+type outputFile struct {
+	URL     string `json:"url"`
+	File    string `json:"file"`
+	Matches int    `json:"matches"`
+	Error   string `json:"error,omitempty"`
+}
+
+// outputFileName returns the name of the result file of target, made of
+// its host and path with anything but letters, digits, dots, dashes and
+// underscores replaced, so it is valid on every OS
+func outputFileName(target string) string {
+	name := target
+	if u, err := url.Parse(target); err == nil && (u.Host != "" || u.Path != "") {
+		name = u.Host + u.Path
+	}
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "._-")
+	if len(name) > maxFileNameLength {
+		name = strings.TrimRight(name[:maxFileNameLength], "._-")
+	}
+	if name == "" {
+		name = "page"
+	}
+	if reservedFileNames.MatchString(name) {
+		name = "_" + name
+	}
+	return name
+}
+
+// writeOutputDir writes the part of r about each scanned URL to its own
+// file in dir, and an index.json listing them. Names that would collide,
+// ignoring case for case-insensitive file systems, get a numbered suffix.
+func writeOutputDir(dir string, r report, stats map[string]objector.Stats, ext string, write func(*os.File, report) error) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	taken := map[string]bool{indexFileName: true}
+	index := outputIndex{Files: []outputFile{}}
+	for _, target := range r.URLs {
+		urlStats, scanned := stats[target]
+		if !scanned {
+			continue
+		}
+		delete(stats, target)
+
+		part := report{URLs: []string{target}, Matches: []objector.Match{}, Stats: urlStats}
+		for _, m := range r.Matches {
+			if m.URL == target {
+				part.Matches = append(part.Matches, m)
+			}
+		}
+		entry := outputFile{URL: target, Matches: len(part.Matches)}
+		for _, e := range r.Errors {
+			if e.URL == target {
+				part.Errors = append(part.Errors, e)
+				entry.Error = e.Error
+			}
+		}
+		for _, redirect := range r.Redirects {
+			if redirect.URL == target {
+				part.Redirects = append(part.Redirects, redirect)
+			}
+		}
+
+		base := outputFileName(target)
+		name := base
+		for n := 2; taken[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[strings.ToLower(name)] = true
+		entry.File = name + ext
+
+		f, err := os.Create(filepath.Join(dir, entry.File))
+		if err != nil {
+			return err
+		}
+		if err := write(f, part); err != nil {
+			f.Close()
+			return fmt.Errorf("writing %s: %w", entry.File, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		index.Files = append(index.Files, entry)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, indexFileName+".json"), append(data, '\n'), 0o644)
+}
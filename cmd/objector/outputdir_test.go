@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+func TestOutputFileName(t *testing.T) {
+	long := "https://a.example/" + strings.Repeat("segment/", 20)
+	tests := []struct {
+		target, want string
+	}{
+		{"https://app.example.com/login", "app.example.com_login"},
+		{"https://app.example.com:8443/a/b?q=1", "app.example.com_8443_a_b"},
+		{"https://app.example.com/", "app.example.com"},
+		{"file:///tmp/page.html", "tmp_page.html"},
+		{"https://con/", "_con"},
+		{"https://con.example/", "_con.example"},
+		{"https://NUL/", "_NUL"},
+		{"https://Com1/", "_Com1"},
+		{"https://com1/x", "com1_x"},
+		{"https://lpt9.example/", "_lpt9.example"},
+		{"https://console.example/", "console.example"},
+		{"https:///", "page"},
+		{"https://comx.example/", "comx.example"},
+		{"https://ünïcode.example/päth", "n_code.example_p_th"},
+		{long, strings.TrimRight(("a.example_" + strings.Repeat("segment_", 20))[:maxFileNameLength], "_")},
+	}
+	for _, tt := range tests {
+		if got := outputFileName(tt.target); got != tt.want {
+			t.Errorf("outputFileName(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+		if got := outputFileName(tt.target); len(got) > maxFileNameLength {
+			t.Errorf("outputFileName(%q) is %d long, want at most %d", tt.target, len(got), maxFileNameLength)
+		}
+	}
+}
+
+func TestWriteOutputDir(t *testing.T) {
+	long := "https://a.example/" + strings.Repeat("x", 150)
+	urls := []string{
+		"https://a.example/page-2",
+		"https://a.example/page",
+		"https://a.example/PAGE",
+		"https://a.example/Page",
+		"https://index/",
+		long,
+		long + "y",
+		"https://down.example/",
+		"https://skipped.example/",
+	}
+	r := report{
+		URLs: urls,
+		Matches: []objector.Match{
+			{URL: "https://a.example/page", Pattern: "JWT"},
+			{URL: "https://a.example/page", Pattern: "JWT"},
+			{URL: "https://a.example/PAGE", Pattern: "JWT"},
+		},
+		Errors: []scanError{{URL: "https://down.example/", Error: "timeout"}},
+	}
+	// The last URL was never scanned, so it gets no file
+	stats := make(map[string]objector.Stats)
+	for _, target := range urls[:len(urls)-1] {
+		stats[target] = objector.Stats{}
+	}
+	dir := filepath.Join(t.TempDir(), "out")
+	err := writeOutputDir(dir, r, stats, ".json", func(f *os.File, part report) error {
+		_, err := fmt.Fprintf(f, "%s %d", part.URLs[0], len(part.Matches))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	longName := "a.example_" + strings.Repeat("x", maxFileNameLength-len("a.example_"))
+	want := []outputFile{
+		{URL: "https://a.example/page-2", File: "a.example_page-2.json"},
+		{URL: "https://a.example/page", File: "a.example_page.json", Matches: 2},
+		// Case-only collisions are numbered, skipping the -2 already taken
+		{URL: "https://a.example/PAGE", File: "a.example_PAGE-3.json", Matches: 1},
+		{URL: "https://a.example/Page", File: "a.example_Page-4.json"},
+		// The index keeps its own name
+		{URL: "https://index/", File: "index-2.json"},
+		// URLs that only differ past the length cap are numbered too
+		{URL: long, File: longName + ".json"},
+		{URL: long + "y", File: longName + "-2.json"},
+		{URL: "https://down.example/", File: "down.example.json", Error: "timeout"},
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index outputIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("index.json is not JSON: %v\n%s", err, data)
+	}
+	if got, want := mustJSON(t, index.Files), mustJSON(t, want); got != want {
+		t.Errorf("index files = %s, want %s", got, want)
+	}
+	for _, file := range want {
+		content, err := os.ReadFile(filepath.Join(dir, file.File))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got, want := string(content), fmt.Sprintf("%s %d", file.URL, file.Matches); got != want {
+			t.Errorf("%s = %q, want %q", file.File, got, want)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want)+1 {
+		t.Errorf("output dir has %d files, want %d and the index", len(entries), len(want))
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// scanTargets calls scan with each target next returns, and its index, up
+// to concurrency at once. A target is only taken once a scan can start, so
+// URLs waited for on stdin are read as the scans free up. It returns when
+// next has no more targets or ctx is done, and every scan started has
+// returned.
+func scanTargets(ctx context.Context, concurrency int, next func(i int) (string, bool), scan func(i int, target string)) {
+	slots := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := 0; ; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		target, ok := next(i)
+		if !ok {
+			break
+		}
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			scan(i, target)
+		}(i, target)
+	}
+	wg.Wait()
+}
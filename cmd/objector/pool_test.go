@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// listTargets returns a next function over targets
+func listTargets(targets []string) func(i int) (string, bool) {
+	return func(i int) (string, bool) {
+		if i >= len(targets) {
+			return "", false
+		}
+		return targets[i], true
+	}
+}
+
+func TestScanTargetsBoundsConcurrency(t *testing.T) {
+	targets := []string{"a", "b", "c", "d", "e", "f", "g"}
+	for _, concurrency := range []int{1, 3, 10} {
+		var running, peak atomic.Int32
+		var mu sync.Mutex
+		var scanned []string
+		scanTargets(context.Background(), concurrency, listTargets(targets), func(i int, target string) {
+			n := running.Add(1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+			if targets[i] != target {
+				t.Errorf("target %d is %q, want %q", i, target, targets[i])
+			}
+			mu.Lock()
+			scanned = append(scanned, target)
+			mu.Unlock()
+		})
+		if got, want := int(peak.Load()), min(concurrency, len(targets)); got != want {
+			t.Errorf("concurrency %d: %d scans ran at once, want %d", concurrency, got, want)
+		}
+		slices.Sort(scanned)
+		if !slices.Equal(scanned, targets) {
+			t.Errorf("concurrency %d: scanned %v, want every target once", concurrency, scanned)
+		}
+	}
+}
+
+func TestScanTargetsInTurn(t *testing.T) {
+	var order []string
+	scanTargets(context.Background(), 1, listTargets([]string{"a", "b", "c"}), func(i int, target string) {
+		order = append(order, target)
+	})
+	if !slices.Equal(order, []string{"a", "b", "c"}) {
+		t.Errorf("scanned %v, want the targets in turn", order)
+	}
+}
+
+func TestScanTargetsStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var scanned atomic.Int32
+	scanTargets(ctx, 2, listTargets([]string{"a", "b", "c", "d", "e"}), func(i int, target string) {
+		if scanned.Add(1) == 1 {
+			cancel()
+		}
+	})
+	// The second slot may have been filled before the cancellation
+	if n := scanned.Load(); n > 2 {
+		t.Errorf("%d targets scanned after the first cancelled the rest", n)
+	}
+}
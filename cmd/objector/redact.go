@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// Number of characters --redact keeps at each end of a value
+const redactKeep = 4
+
+// redactPartial hides the middle of value, keeping the first and last few
+// characters so the secret can still be told apart from others
+func redactPartial(value string) string {
+	runes := []rune(value)
+	keep := redactKeep
+	if len(runes) < 4*keep {
+		keep = len(runes) / 4
+	}
+	return string(runes[:keep]) + strings.Repeat("*", 4) + string(runes[len(runes)-keep:])
+}
+
+// redactFull replaces value with a placeholder giving only its length
+func redactFull(value string) string {
+	return fmt.Sprintf("[REDACTED %d chars]", utf8.RuneCountInString(value))
+}
+
+// compactValue collapses each run of whitespace in value, such as the
+// newlines and indentation of pretty-printed JSON or a PEM key, to a single
+// space
+func compactValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// redactMatch returns match with redact applied to its value, captured
+// group and previous value
+func redactMatch(match objector.Match, redact func(string) string) objector.Match {
+	match.Value = redact(match.Value)
+	if match.Captured != "" {
+		match.Captured = redact(match.Captured)
+	}
+	if match.Previous != "" {
+		match.Previous = redact(match.Previous)
+	}
+	return match
+}
+
+// redactMatches returns copies of matches with redact applied to every value
+func redactMatches(matches []objector.Match, redact func(string) string) []objector.Match {
+	redacted := make([]objector.Match, len(matches))
+	for i, match := range matches {
+		redacted[i] = redactMatch(match, redact)
+	}
+	return redacted
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// sarifLog is a minimal SARIF 2.1.0 document with a single run
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	} `json:"driver"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Properties       struct {
+		SecuritySeverity string `json:"security-severity,omitempty"`
+	} `json:"properties"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful bool                `json:"executionSuccessful"`
+	Notifications       []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	OccurrenceCount     int               `json:"occurrenceCount,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevels maps severities to SARIF result levels
+var sarifLevels = map[objector.Severity]string{
+	objector.SeverityCritical: "error",
+	objector.SeverityHigh:     "error",
+	objector.SeverityMedium:   "warning",
+	objector.SeverityLow:      "note",
+}
+
+// securitySeverities are the CVSS-like scores code scanning dashboards use
+// to rank security rules
+var securitySeverities = map[objector.Severity]string{
+	objector.SeverityCritical: "9.5",
+	objector.SeverityHigh:     "8.0",
+	objector.SeverityMedium:   "5.5",
+	objector.SeverityLow:      "2.0",
+}
+
+// ruleID derives a SARIF rule id from a pattern name, e.g. "AWS Access Key"
+// becomes "aws-access-key"
+func ruleID(pattern string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(pattern) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// writeSARIF writes r as a SARIF 2.1.0 log. Every match is a result
+// located at its URL, with its object paths as logical locations.
+func writeSARIF(w io.Writer, r report) error {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = "objector"
+	run.Tool.Driver.Version = version
+	run.Tool.Driver.InformationURI = "https://github.com/fractalized-cyber/objector"
+
+	rules := make(map[string]sarifRule)
+	for _, m := range r.Matches {
+		id := ruleID(m.Pattern)
+		if _, ok := rules[id]; !ok {
+			rule := sarifRule{ID: id, Name: m.Pattern, ShortDescription: sarifMessage{Text: m.Pattern}}
+			rule.Properties.SecuritySeverity = securitySeverities[m.Severity]
+			rules[id] = rule
+		}
+		level, ok := sarifLevels[m.Severity]
+		if !ok {
+			level = "error"
+		}
+
+		paths := m.Paths
+		if len(paths) == 0 {
+			paths = []string{m.Path}
+		}
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = m.URL
+		for _, path := range paths {
+			location.LogicalLocations = append(location.LogicalLocations, sarifLogicalLocation{
+				FullyQualifiedName: path,
+				Kind:               "object",
+			})
+		}
+
+		// Identifies the same finding across runs without exposing the value
+		fingerprint := sha256.Sum256([]byte(m.Pattern + "\x00" + m.URL + "\x00" + m.Path))
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:              id,
+			Level:               level,
+			Message:             sarifMessage{Text: fmt.Sprintf("%s at %s: %s", m.Description, m.Path, matchValue(m))},
+			Locations:           []sarifLocation{location},
+			PartialFingerprints: map[string]string{"objectorMatch/v1": hex.EncodeToString(fingerprint[:]), "objectorMatchId/v1": m.ID},
+			OccurrenceCount:     m.Count,
+		})
+	}
+
+	run.Tool.Driver.Rules = []sarifRule{}
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	invocation := sarifInvocation{ExecutionSuccessful: len(r.Errors) == 0}
+	for _, e := range r.Errors {
+		invocation.Notifications = append(invocation.Notifications, sarifNotification{
+			Level:   "error",
+			Message: sarifMessage{Text: fmt.Sprintf("could not scan %s: %s", e.URL, e.Error)},
+		})
+	}
+	run.Invocations = []sarifInvocation{invocation}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	r := report{
+		URLs: []string{"https://a.example/", "https://b.example/"},
+		Matches: []objector.Match{
+			{ID: "1111111111111111", URL: "https://a.example/", Pattern: "AWS Access Key", Path: "window.config.key",
+				Value: "AKIA", Description: "AWS Access Key", Severity: objector.SeverityCritical, Count: 2},
+			{ID: "2222222222222222", URL: "https://a.example/", Pattern: "JWT", Path: "window.token",
+				Paths: []string{"window.token", "localStorage['jwt']"}, Value: "eyJ", Description: "JSON Web Token", Severity: objector.SeverityMedium, Count: 1},
+			{ID: "3333333333333333", URL: "https://b.example/", Pattern: "AWS Access Key", Path: "window.key",
+				Value: "AKIB", Description: "AWS Access Key", Severity: objector.SeverityCritical, Count: 1},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("SARIF output is not JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || log.Schema != sarifSchema || len(log.Runs) != 1 {
+		t.Fatalf("log = version %q, schema %q, %d runs, want one SARIF 2.1.0 run", log.Version, log.Schema, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "objector" || run.Tool.Driver.Version != version {
+		t.Errorf("driver = %q %q, want objector %q", run.Tool.Driver.Name, run.Tool.Driver.Version, version)
+	}
+
+	// One rule per pattern, however many matches it has
+	var rules []string
+	for _, rule := range run.Tool.Driver.Rules {
+		rules = append(rules, rule.ID+"="+rule.Name)
+	}
+	if got, want := mustJSON(t, rules), `["aws-access-key=AWS Access Key","jwt=JWT"]`; got != want {
+		t.Errorf("rules = %s, want %s", got, want)
+	}
+
+	if len(run.Results) != len(r.Matches) {
+		t.Fatalf("got %d results, want one per match", len(run.Results))
+	}
+	for i, want := range []struct {
+		ruleID, level, uri string
+		paths              []string
+		count              int
+	}{
+		{"aws-access-key", "error", "https://a.example/", []string{"window.config.key"}, 2},
+		{"jwt", "warning", "https://a.example/", []string{"window.token", "localStorage['jwt']"}, 1},
+		{"aws-access-key", "error", "https://b.example/", []string{"window.key"}, 1},
+	} {
+		result := run.Results[i]
+		if result.RuleID != want.ruleID || result.Level != want.level || result.OccurrenceCount != want.count {
+			t.Errorf("result %d = rule %q, level %q, count %d, want %q, %q, %d", i, result.RuleID, result.Level, result.OccurrenceCount, want.ruleID, want.level, want.count)
+		}
+		if len(result.Locations) != 1 {
+			t.Errorf("result %d has %d locations, want 1", i, len(result.Locations))
+			continue
+		}
+		location := result.Locations[0]
+		if location.PhysicalLocation.ArtifactLocation.URI != want.uri {
+			t.Errorf("result %d artifact = %q, want the URL %q", i, location.PhysicalLocation.ArtifactLocation.URI, want.uri)
+		}
+		var paths []string
+		for _, logical := range location.LogicalLocations {
+			if logical.Kind != "object" {
+				t.Errorf("result %d logical location kind = %q, want object", i, logical.Kind)
+			}
+			paths = append(paths, logical.FullyQualifiedName)
+		}
+		if mustJSON(t, paths) != mustJSON(t, want.paths) {
+			t.Errorf("result %d logical locations = %q, want the paths %q", i, paths, want.paths)
+		}
+		if result.PartialFingerprints["objectorMatchId/v1"] != r.Matches[i].ID {
+			t.Errorf("result %d fingerprints = %v, want the match ID", i, result.PartialFingerprints)
+		}
+	}
+	if len(run.Invocations) != 1 || !run.Invocations[0].ExecutionSuccessful {
+		t.Errorf("invocations = %+v, want one successful", run.Invocations)
+	}
+}
+
+func TestWriteSARIFErrors(t *testing.T) {
+	var buf bytes.Buffer
+	r := report{URLs: []string{"https://a.example/"}, Errors: []scanError{{URL: "https://a.example/", Error: "timeout"}}}
+	if err := writeSARIF(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	invocation := log.Runs[0].Invocations[0]
+	if invocation.ExecutionSuccessful || len(invocation.Notifications) != 1 || invocation.Notifications[0].Message.Text != "could not scan https://a.example/: timeout" {
+		t.Errorf("invocation = %+v, want an unsuccessful one with the error", invocation)
+	}
+	if log.Runs[0].Results == nil || log.Runs[0].Tool.Driver.Rules == nil {
+		t.Errorf("results and rules are null, want empty lists:\n%s", buf.String())
+	}
+}
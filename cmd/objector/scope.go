@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// robotsAgent is the user agent whose robots.txt group applies, besides
+// the "*" group
+const robotsAgent = "objector"
+
+// robotsTimeout bounds the fetch of a robots.txt
+const robotsTimeout = 10 * time.Second
+
+// loadScope reads the URL prefixes of a --scope file, one per line. Blank
+// lines and lines starting with # are skipped.
+func loadScope(path string) ([]*url.URL, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var scope []*url.URL
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		prefix, err := url.Parse(text)
+		if err != nil || prefix.Scheme == "" || prefix.Host == "" {
+			return nil, fmt.Errorf("%s:%d: %q is not an absolute URL", path, line, text)
+		}
+		scope = append(scope, prefix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(scope) == 0 {
+		return nil, fmt.Errorf("%s: no URL prefixes", path)
+	}
+	return scope, nil
+}
+
+// inScope reports whether target starts with one of the scope prefixes.
+// Scheme and host must be equal, so "https://example.com" does not cover
+// "https://example.com.evil.net", and the path must end at a segment
+// boundary, so "/app" covers "/app/login" but not "/apple".
+func inScope(target string, scope []*url.URL) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range scope {
+		if strings.EqualFold(u.Scheme, prefix.Scheme) &&
+			strings.EqualFold(u.Host, prefix.Host) &&
+			underPath(rootedPath(u), rootedPath(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootedPath returns the escaped path of u, "/" if it has none
+func rootedPath(u *url.URL) string {
+	if path := u.EscapedPath(); path != "" {
+		return path
+	}
+	return "/"
+}
+
+// underPath reports whether path is prefix or lies below it
+func underPath(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest := path[len(prefix):]
+	return rest == "" || strings.HasSuffix(prefix, "/") || rest[0] == '/'
+}
+
+// robotsRule is an Allow or Disallow line of a robots.txt
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robots checks URLs against the robots.txt of their hosts, fetching each
+// one once
+type robots struct {
+	client *http.Client
+	rules  map[string][]robotsRule
+}
+
+func newRobots() *robots {
+	return &robots{
+		client: &http.Client{Timeout: robotsTimeout},
+		rules:  make(map[string][]robotsRule),
+	}
+}
+
+// allowed reports whether the robots.txt of target's host lets objector
+// load it. A missing robots.txt allows everything, one that cannot be
+// fetched disallows everything, as RFC 9309 asks.
+func (r *robots) allowed(target string) (bool, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false, err
+	}
+	origin := u.Scheme + "://" + u.Host
+	rules, ok := r.rules[origin]
+	var fetchErr error
+	if !ok {
+		if rules, fetchErr = r.fetch(origin); fetchErr != nil {
+			rules = []robotsRule{{pattern: "/", re: robotsPattern("/")}}
+		}
+		r.rules[origin] = rules
+	}
+
+	// The longest matching rule applies, Allow winning ties
+	path := rootedPath(u)
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	allow, longest := true, -1
+	for _, rule := range rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if n := len(rule.pattern); n > longest || (n == longest && rule.allow) {
+			allow, longest = rule.allow, n
+		}
+	}
+	return allow, fetchErr
+}
+
+// fetch downloads and parses the robots.txt of origin
+func (r *robots) fetch(origin string) ([]robotsRule, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("%s/robots.txt: %s", origin, resp.Status)
+	case resp.StatusCode >= 400:
+		return nil, nil
+	}
+	return parseRobots(bufio.NewScanner(resp.Body)), nil
+}
+
+// parseRobots returns the rules of the group for robotsAgent, or of the "*"
+// group if there is none
+func parseRobots(scanner *bufio.Scanner) []robotsRule {
+	var own, all []robotsRule
+	ownFound := false
+	// Agents of the group being read, and whether its rules have started,
+	// since consecutive User-agent lines share one group
+	var agents []string
+	inRules := false
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agent := strings.ToLower(value)
+			agents = append(agents, agent)
+			ownFound = ownFound || agent == robotsAgent
+		case "allow", "disallow":
+			inRules = true
+			// An empty Disallow allows everything
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value, re: robotsPattern(value)}
+			for _, agent := range agents {
+				switch agent {
+				case robotsAgent:
+					own = append(own, rule)
+				case "*":
+					all = append(all, rule)
+				}
+			}
+		}
+	}
+	if ownFound {
+		return own
+	}
+	return all
+}
+
+// robotsPattern compiles a robots.txt path pattern, where * matches any
+// characters and a trailing $ anchors the end
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// filterTargets drops the targets outside scope, if given, and those the
+// robots.txt rules disallow, if given, warning about each one skipped.
+// Local files are always kept, they are not served by a host.
+func filterTargets(targets []string, scope []*url.URL, rules *robots) []string {
+	var kept []string
+	for _, target := range targets {
+		if isLocal(target) {
+			kept = append(kept, target)
+			continue
+		}
+		if scope != nil && !inScope(target, scope) {
+			fmt.Fprintf(os.Stderr, colorYellow+"Warning: skipped %s, out of scope"+colorReset+"\n", target)
+			continue
+		}
+		if rules != nil {
+			allowed, err := rules.allowed(target)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, colorYellow+"Warning: could not fetch robots.txt, treating the host as disallowed: %v"+colorReset+"\n", err)
+			}
+			if !allowed {
+				fmt.Fprintf(os.Stderr, colorYellow+"Warning: skipped %s, disallowed by robots.txt"+colorReset+"\n", target)
+				continue
+			}
+		}
+		kept = append(kept, target)
+	}
+	return kept
+}
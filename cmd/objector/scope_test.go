@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestInScope(t *testing.T) {
+	var scope []*url.URL
+	for _, prefix := range []string{"https://app.example/app", "https://api.example/v1/", "http://root.example"} {
+		u, err := url.Parse(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scope = append(scope, u)
+	}
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"https://app.example/app", true},
+		{"https://app.example/app/", true},
+		{"https://app.example/app/login?next=/", true},
+		{"https://APP.example/app/login", true},
+		{"https://app.example/apple", false},
+		{"https://app.example/ap", false},
+		{"https://app.example/", false},
+		{"http://app.example/app", false},
+		{"https://app.example.evil.net/app", false},
+		{"https://api.example/v1/users", true},
+		{"https://api.example/v1", false},
+		{"https://api.example/v10/users", false},
+		{"http://root.example", true},
+		{"http://root.example/", true},
+		{"http://root.example/anything/at/all", true},
+		{"http://root.example:8080/", false},
+		{"://bad", false},
+	}
+	for _, tt := range tests {
+		if got := inScope(tt.target, scope); got != tt.want {
+			t.Errorf("inScope(%q) = %t, want %t", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name, robots string
+		want         []string
+	}{
+		{"star group", "User-agent: *\nDisallow: /private\nAllow: /private/ok\n", []string{"-/private", "+/private/ok"}},
+		{"own group wins", "User-agent: *\nDisallow: /\n\nUser-agent: objector\nDisallow: /admin\n", []string{"-/admin"}},
+		{"agent case and comments", "User-Agent: Objector # us\nDisallow: /a # not /b\n", []string{"-/a"}},
+		{"shared group", "User-agent: other\nUser-agent: objector\nDisallow: /shared\nUser-agent: third\nDisallow: /third\n", []string{"-/shared"}},
+		{"empty disallow", "User-agent: *\nDisallow:\n", nil},
+		{"other agents only", "User-agent: other\nDisallow: /\n", nil},
+		{"no colon", "User-agent *\nDisallow /\n", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, rule := range parseRobots(bufio.NewScanner(strings.NewReader(tt.robots))) {
+			sign := "-"
+			if rule.allow {
+				sign = "+"
+			}
+			got = append(got, sign+rule.pattern)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: rules = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/private", "/private", true},
+		{"/private", "/private/page", true},
+		{"/private", "/privateer", true},
+		{"/private", "/public", false},
+		{"/*.php", "/index.php", true},
+		{"/*.php", "/a/b.php?x=1", true},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/a.b", "/axb", false},
+		{"/a+b", "/a+b", true},
+		{"/fish*", "/fish", true},
+		{"/$", "/", true},
+		{"/$", "/page", false},
+	}
+	for _, tt := range tests {
+		if got := robotsPattern(tt.pattern).MatchString(tt.path); got != tt.want {
+			t.Errorf("robotsPattern(%q) matches %q = %t, want %t", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
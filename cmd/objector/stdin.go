@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readTargets reads URLs from r, one per line, skipping blank lines and
+// lines starting with #. The channel is closed at the end of input, so
+// URLs can be scanned while the tool writing them is still running.
+func readTargets(r io.Reader) <-chan string {
+	targets := make(chan string)
+	go func() {
+		defer close(targets)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			targets <- line
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, colorRed+"Error: could not read URLs from stdin: %v"+colorReset+"\n", err)
+		}
+	}()
+	return targets
+}
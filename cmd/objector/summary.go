@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// Number of values and paths listed in the summary
+const summaryTop = 5
+
+// summary groups the matches of a run
+type summary struct {
+	ByPattern []summaryCount `json:"byPattern"`
+	ByValue   []summaryCount `json:"byValue"`
+	TopPaths  []summaryCount `json:"topPaths"`
+}
+
+// summaryCount is the number of matches sharing a pattern, value or path
+type summaryCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func summarize(matches []objector.Match) *summary {
+	byPattern := make(map[string]int)
+	byValue := make(map[string]int)
+	byPath := make(map[string]int)
+
+	for _, match := range matches {
+		paths := match.Paths
+		if len(paths) == 0 {
+			paths = []string{match.Path}
+		}
+		byPattern[match.Pattern] += len(paths)
+		byValue[match.Value] += len(paths)
+		for _, path := range paths {
+			// Group by the object holding the value
+			if i := strings.LastIndex(path, "."); i > 0 {
+				path = path[:i]
+			}
+			byPath[path]++
+		}
+	}
+
+	return &summary{
+		ByPattern: sortedCounts(byPattern, 0),
+		ByValue:   sortedCounts(byValue, summaryTop),
+		TopPaths:  sortedCounts(byPath, summaryTop),
+	}
+}
+
+// sortedCounts orders counts from most to least frequent, keeping at most
+// limit entries unless limit is 0
+func sortedCounts(counts map[string]int, limit int) []summaryCount {
+	sorted := make([]summaryCount, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, summaryCount{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
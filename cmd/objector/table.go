@@ -0,0 +1,540 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fractalized-cyber/objector"
+	"golang.org/x/term"
+)
+
+// Define column widths
+const (
+	severityWidth = 8
+	patternWidth  = 15
+	pathWidth     = 30
+	valueWidth    = 40
+	descWidth     = 30
+
+	// minColumnWidth is the narrowest a fitted column is made
+	minColumnWidth = 8
+	// shortIDLength is the number of hex digits of the ID --show-id shows
+	shortIDLength = 8
+)
+
+// wideRanges are the East Asian wide and fullwidth ranges, including
+// emoji, that take two terminal cells
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F}, {0x2E80, 0x303E}, {0x3041, 0x33FF}, {0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF}, {0xA000, 0xA4CF}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF}, {0x1F900, 0x1F9FF}, {0x1FA70, 0x1FAFF}, {0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+// runeWidth returns the number of terminal cells r takes
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, wide := range wideRanges {
+		if r >= wide[0] && r <= wide[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// displayWidth returns the number of terminal cells s takes
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// pad fills s with spaces to width terminal cells
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-displayWidth(s), 0))
+}
+
+func wrapText(text string, width int) []string {
+	var lines []string
+	// Split by newlines first
+	paragraphs := strings.Split(text, "\n")
+
+	for _, paragraph := range paragraphs {
+		// Then wrap each paragraph
+		runes := []rune(paragraph)
+		for len(runes) > 0 {
+			// Take as many characters as fit, at least one
+			breakPoint, used := 0, 0
+			for breakPoint < len(runes) && used+runeWidth(runes[breakPoint]) <= width {
+				used += runeWidth(runes[breakPoint])
+				breakPoint++
+			}
+			if breakPoint == len(runes) {
+				lines = append(lines, string(runes))
+				break
+			}
+			breakPoint = max(breakPoint, 1)
+
+			// Look for the last space before the break point
+			for i := breakPoint; i > 0; i-- {
+				if runes[i-1] == ' ' {
+					breakPoint = i
+					break
+				}
+			}
+			lines = append(lines, string(runes[:breakPoint]))
+			runes = []rune(strings.TrimSpace(string(runes[breakPoint:])))
+		}
+	}
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	return lines
+}
+
+// tableLayout holds the column widths of the match table
+type tableLayout struct {
+	// timeWidth is the width of the leading time column, 0 to hide it
+	timeWidth                           int
+	severityWidth                       int
+	patternWidth, pathWidth, valueWidth int
+	descWidth                           int
+}
+
+// fixedTableLayout returns the default column widths, used when the
+// table is streamed before the matches are known
+func fixedTableLayout(timeWidth int) tableLayout {
+	return tableLayout{
+		timeWidth:     timeWidth,
+		severityWidth: severityWidth,
+		patternWidth:  patternWidth,
+		pathWidth:     pathWidth,
+		valueWidth:    valueWidth,
+		descWidth:     descWidth,
+	}
+}
+
+// fitTableLayout sizes every column to its widest cell, then narrows the
+// widest columns until the table fits maxWidth, if positive. Cells are
+// wrapped to the resulting widths.
+func fitTableLayout(showTime bool, rows [][6]string, maxWidth int) tableLayout {
+	widths := []int{0, len("Severity"), len("Pattern"), len("Path"), len("Value"), len("Description")}
+	if showTime {
+		widths[0] = len("Time")
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == 0 && !showTime {
+				continue
+			}
+			for _, line := range strings.Split(cell, "\n") {
+				widths[i] = max(widths[i], displayWidth(line))
+			}
+		}
+	}
+
+	// Each column adds its padding and left border, plus the right border
+	total := func() int {
+		sum := 1
+		for _, width := range widths {
+			if width > 0 {
+				sum += width + 3
+			}
+		}
+		return sum
+	}
+	for maxWidth > 0 && total() > maxWidth {
+		widest := 0
+		for i, width := range widths {
+			if width > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+	}
+
+	return tableLayout{
+		timeWidth:     widths[0],
+		severityWidth: widths[1],
+		patternWidth:  widths[2],
+		pathWidth:     widths[3],
+		valueWidth:    widths[4],
+		descWidth:     widths[5],
+	}
+}
+
+// widths returns the width of every column, in order
+func (l tableLayout) widths() []int {
+	widths := []int{l.severityWidth, l.patternWidth, l.pathWidth, l.valueWidth, l.descWidth}
+	if l.timeWidth > 0 {
+		widths = append([]int{l.timeWidth}, widths...)
+	}
+	return widths
+}
+
+// border draws a horizontal rule across the columns
+func (l tableLayout) border(left, middle, right string) string {
+	var segments []string
+	for _, width := range l.widths() {
+		segments = append(segments, strings.Repeat(boxHorizontal, width+2))
+	}
+	return left + strings.Join(segments, middle) + right
+}
+
+func printTableHeader(w *os.File, l tableLayout) {
+	// Print top border
+	fmt.Fprintln(w, l.border(boxTopLeft, boxTopTee, boxTopRight))
+
+	// Print header
+	titles := []string{"Severity", "Pattern", "Path", "Value", "Description"}
+	if l.timeWidth > 0 {
+		titles = append([]string{"Time"}, titles...)
+	}
+	for i, width := range l.widths() {
+		title := pad(truncate(titles[i], width), width)
+		if i == 0 {
+			title = colorBold + title + colorReset
+		}
+		fmt.Fprintf(w, boxVertical+" %s ", title)
+	}
+	fmt.Fprintln(w, boxVertical)
+
+	// Print header separator
+	fmt.Fprintln(w, l.border(boxLeftTee, boxCross, boxRightTee))
+}
+
+// matchRow returns the cells of a match: time, severity, pattern, path,
+// value and description, followed by how often it was seen if more than
+// once and the short ID if showID is set
+func matchRow(m objector.Match, timestampFormat string, showID bool) [6]string {
+	description := m.Description
+	if m.Previous != "" {
+		description += "\nchanged from " + m.Previous
+	}
+	if m.Count > 1 {
+		description += "\nseen " + plural(m.Count, "time", "times")
+	}
+	if showID {
+		description += "\nid " + m.ID[:min(len(m.ID), shortIDLength)]
+	}
+	return [6]string{formatTimestamp(m.Timestamp, timestampFormat), string(m.Severity), m.Pattern, m.Path, matchValue(m), description}
+}
+
+// matchValue is the value shown for a match, its captured group if the
+// pattern has one
+func matchValue(m objector.Match) string {
+	if m.Captured != "" {
+		return m.Captured
+	}
+	return m.Value
+}
+
+// printMatchTable lays out the whole table to fit the terminal behind w
+func printMatchTable(w *os.File, matches []objector.Match, showTime bool, timestampFormat string, showID bool) {
+	rows := make([][6]string, len(matches))
+	for i, m := range matches {
+		rows[i] = matchRow(m, timestampFormat, showID)
+	}
+	termWidth, _, err := term.GetSize(int(w.Fd()))
+	if err != nil {
+		termWidth = 0
+	}
+
+	layout := fitTableLayout(showTime, rows, termWidth)
+	printTableHeader(w, layout)
+	for _, row := range rows {
+		printTableRow(w, layout, row)
+	}
+}
+
+// printPatternTable prints the name, severity, regex and description of
+// each pattern, wrapping the regexes and descriptions to fit the terminal
+func printPatternTable(w *os.File, patterns []objector.Pattern) {
+	titles := []string{"Name", "Severity", "Regex", "Description"}
+	rows := make([][]string, len(patterns))
+	widths := make([]int, len(titles))
+	for i, title := range titles {
+		widths[i] = displayWidth(title)
+	}
+	for i, p := range patterns {
+		rows[i] = []string{p.Name, string(p.Severity), p.Pattern, p.Description}
+		for j, cell := range rows[i] {
+			widths[j] = max(widths[j], displayWidth(cell))
+		}
+	}
+
+	// Only the regex and description columns are narrowed, the widest first
+	termWidth, _, err := term.GetSize(int(w.Fd()))
+	if err == nil {
+		total := 1
+		for _, width := range widths {
+			total += width + 3
+		}
+		for ; total > termWidth; total-- {
+			widest := 2
+			if widths[3] > widths[2] {
+				widest = 3
+			}
+			if widths[widest] <= minColumnWidth {
+				break
+			}
+			widths[widest]--
+		}
+	}
+
+	border := func(left, middle, right string) string {
+		segments := make([]string, len(widths))
+		for i, width := range widths {
+			segments[i] = strings.Repeat(boxHorizontal, width+2)
+		}
+		return left + strings.Join(segments, middle) + right
+	}
+	fmt.Fprintln(w, border(boxTopLeft, boxTopTee, boxTopRight))
+	for i, title := range titles {
+		fmt.Fprintf(w, boxVertical+" %s%s%s ", colorBold, pad(title, widths[i]), colorReset)
+	}
+	fmt.Fprintln(w, boxVertical)
+	fmt.Fprintln(w, border(boxLeftTee, boxCross, boxRightTee))
+	for _, row := range rows {
+		columns := make([][]string, len(row))
+		lines := 0
+		for i, cell := range row {
+			columns[i] = wrapText(cell, widths[i])
+			lines = max(lines, len(columns[i]))
+		}
+		color := severityColor(objector.Severity(row[1]))
+		for i := 0; i < lines; i++ {
+			for j, column := range columns {
+				cell := ""
+				if i < len(column) {
+					cell = column[i]
+				}
+				cell = pad(cell, widths[j])
+				if j < 2 {
+					cell = color + cell + colorReset
+				}
+				fmt.Fprintf(w, boxVertical+" %s ", cell)
+			}
+			fmt.Fprintln(w, boxVertical)
+		}
+	}
+	fmt.Fprintln(w, border(boxBottomLeft, boxBottomTee, boxBottomRight))
+}
+
+func printTableRow(w *os.File, l tableLayout, row [6]string) {
+	// Wrap each field
+	fields := row[1:]
+	if l.timeWidth > 0 {
+		fields = row[:]
+	}
+	widths := l.widths()
+	columns := make([][]string, len(fields))
+	for i, field := range fields {
+		columns[i] = wrapText(field, widths[i])
+	}
+
+	// Find the maximum number of lines needed
+	maxLines := 0
+	for _, lines := range columns {
+		if len(lines) > maxLines {
+			maxLines = len(lines)
+		}
+	}
+
+	// The severity and pattern are colored by severity
+	patternColumn := len(fields) - 4
+	color := severityColor(objector.Severity(row[1]))
+
+	// Print each line
+	for i := 0; i < maxLines; i++ {
+		for j, lines := range columns {
+			cell := ""
+			if i < len(lines) {
+				cell = lines[i]
+			}
+			cell = pad(cell, widths[j])
+			if j == patternColumn || j == patternColumn-1 {
+				cell = color + cell + colorReset
+			}
+			fmt.Fprintf(w, boxVertical+" %s ", cell)
+		}
+		fmt.Fprintln(w, boxVertical)
+	}
+
+	// Print bottom border for the last row
+	if maxLines > 0 {
+		fmt.Fprintln(w, l.border(boxBottomLeft, boxBottomTee, boxBottomRight))
+	}
+}
+
+func printStats(w *os.File, stats objector.Stats) {
+	fmt.Fprintln(w, "\n"+boxTopLeft+strings.Repeat(boxHorizontal, 50)+boxTopRight)
+	fmt.Fprintln(w, boxVertical+" "+colorBold+"Monitoring Statistics"+colorReset+strings.Repeat(" ", 28)+boxVertical)
+	fmt.Fprintln(w, boxLeftTee+strings.Repeat(boxHorizontal, 50)+boxRightTee)
+	fmt.Fprintf(w, boxVertical+" Total Objects Scanned: %-25d "+boxVertical+"\n", stats.ObjectsScanned)
+	fmt.Fprintf(w, boxVertical+" Total Matches Found:   %-25d "+boxVertical+"\n", stats.MatchesFound)
+	if stats.ExistingMatches > 0 {
+		fmt.Fprintf(w, boxVertical+" Existing Matches:      %-25d "+boxVertical+"\n", stats.ExistingMatches)
+	}
+	fmt.Fprintf(w, boxVertical+" Pages Scanned:         %-25d "+boxVertical+"\n", stats.PagesScanned)
+	if stats.TruncatedScans > 0 {
+		fmt.Fprintf(w, boxVertical+" Scans Over Budget:     %-25d "+boxVertical+"\n", stats.TruncatedScans)
+	}
+	if stats.CappedScans > 0 {
+		fmt.Fprintf(w, boxVertical+" Scans at Match Cap:    %-25d "+boxVertical+"\n", stats.CappedScans)
+	}
+	if stats.StoppedEarly > 0 {
+		fmt.Fprintf(w, boxVertical+" Stopped at Limit:      %-25s "+boxVertical+"\n", fmt.Sprintf("%d of %d pages", stats.StoppedEarly, stats.PagesScanned))
+	}
+	if len(stats.FailedInterceptors) > 0 {
+		// Live interception is degraded, scan passes still cover the page
+		for i, line := range wrapText(strings.Join(stats.FailedInterceptors, ", "), 25) {
+			label := ""
+			if i == 0 {
+				label = "Failed Interceptors:"
+			}
+			fmt.Fprintf(w, boxVertical+" %-22s %s%s%s "+boxVertical+"\n", label, colorYellow, pad(line, 25), colorReset)
+		}
+	}
+	if len(stats.DisabledPatterns) > 0 {
+		// Too slow in the browser, likely backtracking catastrophically
+		for i, line := range wrapText(strings.Join(stats.DisabledPatterns, ", "), 25) {
+			label := ""
+			if i == 0 {
+				label = "Disabled Patterns:"
+			}
+			fmt.Fprintf(w, boxVertical+" %-22s %s%s%s "+boxVertical+"\n", label, colorYellow, pad(line, 25), colorReset)
+		}
+	}
+	fmt.Fprintf(w, boxVertical+" Duration:              %-25s "+boxVertical+"\n", stats.Duration.Round(time.Millisecond))
+	fmt.Fprintln(w, boxBottomLeft+strings.Repeat(boxHorizontal, 50)+boxBottomRight)
+}
+
+// printProfile prints where the time of the scan went. Time not spent in a
+// phase was spent waiting for the timeout between passes.
+func printProfile(w *os.File, stats objector.Stats) {
+	p := stats.Profile
+	waiting := stats.Duration - p.Launch - p.Navigation - p.Setup - p.Injection - p.Scanning
+	average := time.Duration(0)
+	if len(p.Passes) > 0 {
+		average = p.Scanning / time.Duration(len(p.Passes))
+	}
+	ms := func(d time.Duration) string { return max(d, 0).Round(time.Millisecond).String() }
+
+	fmt.Fprintln(w, "\n"+boxTopLeft+strings.Repeat(boxHorizontal, 50)+boxTopRight)
+	fmt.Fprintln(w, boxVertical+" "+colorBold+"Profile"+colorReset+strings.Repeat(" ", 42)+boxVertical)
+	fmt.Fprintln(w, boxLeftTee+strings.Repeat(boxHorizontal, 50)+boxRightTee)
+	fmt.Fprintf(w, boxVertical+" Browser Launch:        %-25s "+boxVertical+"\n", ms(p.Launch))
+	fmt.Fprintf(w, boxVertical+" Navigation:            %-25s "+boxVertical+"\n", ms(p.Navigation))
+	fmt.Fprintf(w, boxVertical+" Setup and Actions:     %-25s "+boxVertical+"\n", ms(p.Setup))
+	fmt.Fprintf(w, boxVertical+" Injection:             %-25s "+boxVertical+"\n", ms(p.Injection))
+	fmt.Fprintf(w, boxVertical+" Scanning:              %-25s "+boxVertical+"\n", fmt.Sprintf("%s in %s", ms(p.Scanning), plural(len(p.Passes), "pass", "passes")))
+	fmt.Fprintf(w, boxVertical+" Waiting:               %-25s "+boxVertical+"\n", ms(waiting))
+	fmt.Fprintln(w, boxLeftTee+strings.Repeat(boxHorizontal, 50)+boxRightTee)
+	fmt.Fprintf(w, boxVertical+" Average Pass:          %-25s "+boxVertical+"\n", ms(average))
+	fmt.Fprintf(w, boxVertical+" Slowest Pass:          %-25s "+boxVertical+"\n", ms(p.SlowestPass()))
+	fmt.Fprintf(w, boxVertical+" Objects per Second:    %-25s "+boxVertical+"\n", formatCount(int(p.ObjectsPerSecond())))
+	fmt.Fprintln(w, boxBottomLeft+strings.Repeat(boxHorizontal, 50)+boxBottomRight)
+}
+
+func printSummary(w *os.File, s *summary) {
+	fmt.Fprintln(w, "\n"+boxTopLeft+strings.Repeat(boxHorizontal, 50)+boxTopRight)
+	fmt.Fprintln(w, boxVertical+" "+colorBold+"Summary"+colorReset+strings.Repeat(" ", 42)+boxVertical)
+
+	sections := []struct {
+		title  string
+		counts []summaryCount
+	}{
+		{"Matches by Pattern", s.ByPattern},
+		{"Most Common Values", s.ByValue},
+		{"Top Object Paths", s.TopPaths},
+	}
+	for _, section := range sections {
+		fmt.Fprintln(w, boxLeftTee+strings.Repeat(boxHorizontal, 50)+boxRightTee)
+		fmt.Fprintf(w, boxVertical+" %-48s "+boxVertical+"\n", section.title+":")
+		if len(section.counts) == 0 {
+			fmt.Fprintf(w, boxVertical+"   %-46s "+boxVertical+"\n", "none")
+		}
+		for _, c := range section.counts {
+			fmt.Fprintf(w, boxVertical+"   %s %5d "+boxVertical+"\n", pad(truncate(c.Name, 40), 40), c.Count)
+		}
+	}
+	fmt.Fprintln(w, boxBottomLeft+strings.Repeat(boxHorizontal, 50)+boxBottomRight)
+}
+
+// printDiff prints the matches that are new, removed and persisting since
+// the baseline
+func printDiff(w *os.File, d *diff) {
+	fmt.Fprintln(w, "\n"+boxTopLeft+strings.Repeat(boxHorizontal, 50)+boxTopRight)
+	fmt.Fprintln(w, boxVertical+" "+colorBold+"Diff"+colorReset+strings.Repeat(" ", 45)+boxVertical)
+
+	sections := []struct {
+		title   string
+		color   string
+		matches []objector.Match
+	}{
+		{"New", colorRed, d.New},
+		{"Removed", colorGreen, d.Removed},
+		{"Persisting", colorYellow, d.Persisting},
+	}
+	for _, section := range sections {
+		fmt.Fprintln(w, boxLeftTee+strings.Repeat(boxHorizontal, 50)+boxRightTee)
+		fmt.Fprintf(w, boxVertical+" %s%s%s "+boxVertical+"\n", section.color, pad(fmt.Sprintf("%s (%d):", section.title, len(section.matches)), 48), colorReset)
+		for _, m := range section.matches {
+			fmt.Fprintf(w, boxVertical+"   %s "+boxVertical+"\n", pad(truncate(m.Pattern+" at "+m.Path+": "+m.Value, 46), 46))
+		}
+	}
+	fmt.Fprintln(w, boxBottomLeft+strings.Repeat(boxHorizontal, 50)+boxBottomRight)
+}
+
+// formatCount renders n with thousands separators, e.g. 12,431
+func formatCount(n int) string {
+	digits := strconv.Itoa(n)
+	start := 0
+	if n < 0 {
+		start = 1
+	}
+	for i := len(digits) - 3; i > start; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
+
+// plural renders a count with the singular or plural noun
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return formatCount(n) + " " + singular
+	}
+	return formatCount(n) + " " + pluralForm
+}
+
+// truncate shortens s to at most width terminal cells, marking the cut with
+// an ellipsis, itself cut when width leaves no room for the text
+func truncate(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+	if width < 3 {
+		return strings.Repeat(".", max(width, 0))
+	}
+	used := 0
+	for i, r := range s {
+		if used+runeWidth(r) > width-3 {
+			return s[:i] + "..."
+		}
+		used += runeWidth(r)
+	}
+	return s
+}
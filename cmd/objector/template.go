@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// builtinTemplates are the named templates accepted by --template
+var builtinTemplates = map[string]string{
+	// markdown renders the matches as a Markdown table
+	"markdown": `| Severity | Pattern | Path | Value | Description |
+| --- | --- | --- | --- | --- |
+{{range .Matches}}| {{.Severity}} | {{md .Pattern}} | {{md .Path}} | {{md .Value}} | {{md .Description}} |
+{{end}}`,
+	// log writes one key=value line per match
+	"log": `{{range .Matches}}{{timestamp .Timestamp}} id={{.ID}} severity={{.Severity}} pattern={{printf "%q" .Pattern}} url={{printf "%q" .URL}} path={{printf "%q" .Path}} value={{printf "%q" .Value}}{{with .Captured}} captured={{printf "%q" .}}{{end}}{{with .Context}} before={{printf "%q" .Before}} after={{printf "%q" .After}}{{end}}
+{{end}}`,
+}
+
+// markdownEscaper keeps a value inside its Markdown table cell
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "")
+
+// parseTemplate loads the template for --format template from a file, a
+// built-in name or inline text. Timestamps are rendered in timestampFormat.
+func parseTemplate(file, text, timestampFormat string) (*template.Template, error) {
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	case builtinTemplates[text] != "":
+		text = builtinTemplates[text]
+	case text == "":
+		return nil, fmt.Errorf("--format template needs --template or --template-file")
+	}
+
+	funcs := template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"md": markdownEscaper.Replace,
+		"timestamp": func(t time.Time) string {
+			if timestampFormat == "" {
+				return t.Format(time.RFC3339)
+			}
+			return formatTimestamp(t, timestampFormat)
+		},
+	}
+	return template.New("output").Funcs(funcs).Parse(text)
+}
+
+// writeTemplate renders the whole result through t
+func writeTemplate(w io.Writer, t *template.Template, r report) error {
+	return t.Execute(w, r)
+}
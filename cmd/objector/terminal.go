@@ -0,0 +1,29 @@
+package main
+
+// Box drawing characters of the table and panel borders, replaced by ASCII
+// when the terminal cannot show them
+var (
+	boxHorizontal  = "─"
+	boxVertical    = "│"
+	boxTopLeft     = "┌"
+	boxTopRight    = "┐"
+	boxBottomLeft  = "└"
+	boxBottomRight = "┘"
+	boxLeftTee     = "├"
+	boxRightTee    = "┤"
+	boxTopTee      = "┬"
+	boxBottomTee   = "┴"
+	boxCross       = "┼"
+)
+
+// spinnerFrames are the animation frames of the progress spinner
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// setASCII draws borders with plain +, - and | characters and the spinner
+// with ASCII frames
+func setASCII() {
+	boxHorizontal, boxVertical = "-", "|"
+	boxTopLeft, boxTopRight, boxBottomLeft, boxBottomRight = "+", "+", "+", "+"
+	boxLeftTee, boxRightTee, boxTopTee, boxBottomTee, boxCross = "+", "+", "+", "+", "+"
+	spinnerFrames = []string{"|", "/", "-", "\\"}
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+// enableVirtualTerminal reports whether the terminal understands ANSI
+// escape sequences, which every supported terminal outside Windows does
+func enableVirtualTerminal() bool {
+	return true
+}
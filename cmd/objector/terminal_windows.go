@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVirtualTerminal turns on ANSI escape sequence processing of the
+// consoles stdout and stderr write to, and reports whether stdout
+// understands them. Consoles older than Windows 10 do not. Output that is
+// not a console is left alone.
+func enableVirtualTerminal() bool {
+	enabled := true
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		handle := windows.Handle(f.Fd())
+		var mode uint32
+		if err := windows.GetConsoleMode(handle, &mode); err != nil {
+			continue
+		}
+		err := windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+		if err != nil && f == os.Stdout {
+			enabled = false
+		}
+	}
+	return enabled
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Named timestamp formats, any other format is a Go time layout
+const (
+	timestampRFC3339 = "rfc3339"
+	timestampUnix    = "unix"
+
+	// defaultTimestampLayout is used by the table when no format is given
+	defaultTimestampLayout = "2006-01-02 15:04:05"
+)
+
+// validateTimestampFormat rejects layouts that contain no time elements,
+// which would render every timestamp as the same literal text
+func validateTimestampFormat(format string) error {
+	switch format {
+	case "", timestampRFC3339, timestampUnix:
+		return nil
+	}
+	if time.Unix(0, 0).Format(format) == format {
+		return fmt.Errorf("%q is not a Go time layout, rfc3339 or unix", format)
+	}
+	return nil
+}
+
+// formatTimestamp renders t in the given format
+func formatTimestamp(t time.Time, format string) string {
+	switch format {
+	case "":
+		return t.Format(defaultTimestampLayout)
+	case timestampRFC3339:
+		return t.Format(time.RFC3339)
+	case timestampUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.Format(format)
+}
+
+// timestampJSON returns t as it is encoded in JSON output, unix timestamps
+// being numbers
+func timestampJSON(t time.Time, format string) interface{} {
+	if format == timestampUnix {
+		return t.Unix()
+	}
+	return formatTimestamp(t, format)
+}
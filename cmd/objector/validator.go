@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// Verdicts a --validator command may print
+const (
+	verdictValid   = "valid"
+	verdictInvalid = "invalid"
+	verdictUnknown = "unknown"
+)
+
+// validatorWaitDelay is how long output is still read after a validator
+// command timed out
+const validatorWaitDelay = 100 * time.Millisecond
+
+// validator runs a user command to judge matches. The command gets the
+// match as JSON on stdin and prints its verdict on the first line of stdout.
+type validator struct {
+	command     string
+	concurrency int
+	timeout     time.Duration
+}
+
+// annotate runs the command for every match not verified yet, up to
+// concurrency at a time, and records valid and invalid verdicts on the
+// matches. Failures are warned about and count as unknown.
+func (v validator) annotate(ctx context.Context, matches []objector.Match) {
+	sem := make(chan struct{}, max(v.concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range matches {
+		if matches[i].Verified != nil {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(m *objector.Match) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			verdict, err := v.run(ctx, *m)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, colorYellow+"Warning: validator failed for %s at %s: %v"+colorReset+"\n", m.Pattern, m.Path, err)
+				return
+			}
+			if verdict != verdictUnknown {
+				valid := verdict == verdictValid
+				m.Verified = &valid
+				m.Description += " (validator: " + verdict + ")"
+			}
+		}(&matches[i])
+	}
+	wg.Wait()
+}
+
+// run asks the command for its verdict on m
+func (v validator) run(ctx context.Context, m objector.Match) (string, error) {
+	input, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", v.command)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stderr = os.Stderr
+	// Children of the shell may hold stdout open after it is killed
+	cmd.WaitDelay = validatorWaitDelay
+	output, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("no verdict within %s", v.timeout)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	line, _, _ := strings.Cut(string(output), "\n")
+	switch verdict := strings.ToLower(strings.TrimSpace(line)); verdict {
+	case verdictValid, verdictInvalid, verdictUnknown:
+		return verdict, nil
+	default:
+		return "", fmt.Errorf("unexpected verdict %q, expected valid, invalid or unknown", verdict)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// stsEndpoint answers the sts:GetCallerIdentity calls of --verify-aws
+var stsEndpoint = "https://sts.amazonaws.com/"
+
+const (
+	// stsRegion is the signing region of the global STS endpoint
+	stsRegion = "us-east-1"
+	// verifyInterval is the minimum time between two verification calls
+	verifyInterval = time.Second
+	// verifyTimeout bounds a single verification call
+	verifyTimeout = 10 * time.Second
+	// maxVerifyPairs caps the key and secret pairs tried for one page
+	maxVerifyPairs = 10
+)
+
+var (
+	awsKeyID     = regexp.MustCompile(`AKIA[A-Z0-9]{16}`)
+	awsSecretRun = regexp.MustCompile(`[A-Za-z0-9/+]+`)
+)
+
+// awsVerifier checks AWS access keys found together with a secret key by
+// calling sts:GetCallerIdentity, which any valid key may call and which
+// reads and changes nothing. Each pair is only tried once, and the pages
+// scanned at once are verified one at a time.
+type awsVerifier struct {
+	client  *http.Client
+	mu      sync.Mutex
+	next    time.Time
+	results map[[2]string]bool
+}
+
+func newAWSVerifier() *awsVerifier {
+	return &awsVerifier{
+		client:  &http.Client{Timeout: verifyTimeout},
+		results: make(map[[2]string]bool),
+	}
+}
+
+// verifyMatches pairs the AWS access keys among matches, all from one page,
+// with its AWS secret keys and marks both of a pair that AWS accepts as
+// verified. A key or secret is marked unverified once every pair it is in
+// has been rejected, and left unmarked if it has no pair or a call failed.
+func (v *awsVerifier) verifyMatches(matches []objector.Match) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var keys, secrets []int
+	for i, m := range matches {
+		switch m.Pattern {
+		case "AWS Access Key":
+			keys = append(keys, i)
+		case "AWS Secret Key":
+			secrets = append(secrets, i)
+		}
+	}
+
+	// Whether each match is in an accepted pair, and how many of its pairs
+	// were not rejected, because the call failed or was never made
+	live := make(map[int]bool)
+	open := make(map[int]int)
+	tried := 0
+	for _, k := range keys {
+		keyID := awsKeyID.FindString(matchValue(matches[k]))
+		if keyID == "" {
+			continue
+		}
+		for _, s := range secrets {
+			for _, secret := range awsSecrets(matchValue(matches[s])) {
+				open[k]++
+				open[s]++
+				if tried >= maxVerifyPairs {
+					continue
+				}
+				tried++
+				ok, err := v.check(keyID, secret)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, colorYellow+"Warning: could not verify an AWS key: %v"+colorReset+"\n", err)
+					continue
+				}
+				if ok {
+					live[k], live[s] = true, true
+				}
+				open[k]--
+				open[s]--
+			}
+		}
+	}
+
+	for i := range matches {
+		if live[i] {
+			markVerified(&matches[i], true)
+		} else if count, paired := open[i]; paired && count == 0 {
+			markVerified(&matches[i], false)
+		}
+	}
+}
+
+// markVerified records the outcome of verification on m
+func markVerified(m *objector.Match, verified bool) {
+	m.Verified = &verified
+	if verified {
+		m.Description += " (verified live)"
+	} else {
+		m.Description += " (rejected by AWS)"
+	}
+}
+
+// awsSecrets returns the candidate secret keys in value, the 40 character
+// runs of the base64 alphabet
+func awsSecrets(value string) []string {
+	var secrets []string
+	for _, run := range awsSecretRun.FindAllString(value, -1) {
+		if len(run) == 40 && !slices.Contains(secrets, run) {
+			secrets = append(secrets, run)
+		}
+	}
+	return secrets
+}
+
+// check reports whether AWS accepts the key pair, waiting out the rate
+// limit first
+func (v *awsVerifier) check(keyID, secret string) (bool, error) {
+	pair := [2]string{keyID, secret}
+	if ok, done := v.results[pair]; done {
+		return ok, nil
+	}
+	time.Sleep(time.Until(v.next))
+	v.next = time.Now().Add(verifyInterval)
+
+	ok, err := getCallerIdentity(v.client, keyID, secret, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	v.results[pair] = ok
+	return ok, nil
+}
+
+// getCallerIdentity calls sts:GetCallerIdentity with the key pair. It
+// reports false when AWS rejects the key or the secret, and an error when
+// the call could not tell.
+func getCallerIdentity(client *http.Client, keyID, secret string, now time.Time) (bool, error) {
+	body := "Action=GetCallerIdentity&Version=2011-06-15"
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, stsEndpoint, strings.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signSTS(req, body, keyID, secret, now)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return true, nil
+	}
+
+	var stsError struct {
+		Code string `xml:"Error>Code"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	xml.Unmarshal(data, &stsError)
+	switch stsError.Code {
+	case "InvalidClientTokenId", "SignatureDoesNotMatch":
+		return false, nil
+	case "":
+		return false, fmt.Errorf("STS responded with %s", resp.Status)
+	}
+	return false, fmt.Errorf("STS responded with %s: %s", resp.Status, stsError.Code)
+}
+
+// signSTS adds an AWS Signature Version 4 to a request for STS
+func signSTS(req *http.Request, body, keyID, secret string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "content-type;host;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"content-type:" + req.Header.Get("Content-Type") + "\n" +
+			"host:" + req.URL.Host + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+	scope := date + "/" + stsRegion + "/sts/aws4_request"
+	signature := sigV4Signature(secret, amzDate, stsRegion, "sts", canonicalRequest)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+keyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// sigV4Signature signs a canonical request with the key derived from secret
+// for the date, region and service
+func sigV4Signature(secret, amzDate, region, service, canonicalRequest string) string {
+	date := amzDate[:8]
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
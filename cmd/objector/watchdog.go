@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Exit status of a run stopped by --max-runtime, the same as timeout(1)
+const exitMaxRuntime = 124
+
+// maxRuntimeMargin is how long a run stopped by --max-runtime has to
+// report its results and close the browser before it is killed
+const maxRuntimeMargin = 10 * time.Second
+
+// errMaxRuntime is the cause of the cancellation of a run stopped by
+// --max-runtime
+var errMaxRuntime = errors.New("maximum runtime exceeded")
+
+// startWatchdog cancels the run with errMaxRuntime once it has taken limit,
+// so partial results are still reported, and exits the process if it has
+// not ended maxRuntimeMargin later, as when Chrome or the allocator hang
+func startWatchdog(limit time.Duration, cancel context.CancelCauseFunc) {
+	time.AfterFunc(limit, func() {
+		fmt.Fprintf(os.Stderr, colorRed+"Error: the run exceeded --max-runtime of %s, stopping"+colorReset+"\n", limit)
+		cancel(errMaxRuntime)
+		time.AfterFunc(maxRuntimeMargin, func() {
+			fmt.Fprintf(os.Stderr, colorRed+"Error: the run did not stop within %s of --max-runtime, exiting"+colorReset+"\n", maxRuntimeMargin)
+			os.Exit(exitMaxRuntime)
+		})
+	})
+}
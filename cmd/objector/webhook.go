@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+const (
+	// webhookAttempts is how many times a match is POSTed before giving up
+	webhookAttempts = 3
+	// webhookTimeout bounds a single delivery attempt
+	webhookTimeout = 10 * time.Second
+	// webhookBackoff is the delay before the first retry, doubled for each
+	// one after it
+	webhookBackoff = time.Second
+	// webhookQueueSize is how many matches wait for delivery before new
+	// ones are dropped
+	webhookQueueSize = 100
+)
+
+// webhook POSTs matches as JSON to an endpoint from a background queue, so
+// a slow or failing endpoint never holds up the scan. Matches that find the
+// queue full are dropped, and those dropped or never delivered are counted
+// in a single warning when the webhook is closed.
+type webhook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	backoff time.Duration
+	queue   chan objector.Match
+	done    chan struct{}
+	dropped atomic.Int64
+	// failed is only touched by the delivering goroutine until done
+	failed int
+}
+
+// newWebhook validates endpoint and starts delivering to it
+func newWebhook(endpoint string, headers map[string]string) (*webhook, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q", endpoint)
+	}
+
+	w := &webhook{
+		url:     endpoint,
+		headers: headers,
+		client:  &http.Client{Timeout: webhookTimeout},
+		backoff: webhookBackoff,
+		queue:   make(chan objector.Match, webhookQueueSize),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		for match := range w.queue {
+			w.deliver(match)
+		}
+	}()
+	return w, nil
+}
+
+// send queues a match for delivery, dropping it if the queue is full
+func (w *webhook) send(match objector.Match) {
+	select {
+	case w.queue <- match:
+	default:
+		w.dropped.Add(1)
+		slog.Debug("webhook queue full, dropping match", "pattern", match.Pattern, "path", match.Path)
+	}
+}
+
+// close waits for the queued matches to be delivered, warning about those
+// that were not
+func (w *webhook) close() {
+	close(w.queue)
+	<-w.done
+	if dropped := w.dropped.Load(); dropped > 0 || w.failed > 0 {
+		fmt.Fprintf(os.Stderr, colorYellow+"Warning: the webhook missed %s, %d dropped as the queue was full and %d failed (see --debug)"+colorReset+"\n",
+			plural(int(dropped)+w.failed, "match", "matches"), dropped, w.failed)
+	}
+}
+
+// deliver POSTs a match, retrying failures with a growing delay
+func (w *webhook) deliver(match objector.Match) {
+	body, err := json.Marshal(match)
+	if err != nil {
+		w.failed++
+		slog.Debug("webhook: could not encode match", "error", err)
+		return
+	}
+
+	backoff := w.backoff
+	for attempt := 1; ; attempt++ {
+		err := w.post(body)
+		if err == nil {
+			return
+		}
+		if attempt >= webhookAttempts {
+			w.failed++
+			slog.Debug("webhook delivery failed", "pattern", match.Pattern, "path", match.Path, "attempts", attempt, "error", err)
+			return
+		}
+		slog.Debug("webhook delivery failed, retrying", "attempt", attempt, "attempts", webhookAttempts, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *webhook) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fractalized-cyber/objector"
+)
+
+// testWebhook returns a webhook to handler that retries without waiting
+func testWebhook(t *testing.T, handler http.HandlerFunc) *webhook {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	w, err := newWebhook(server.URL+"/hook", map[string]string{"Authorization": "Bearer x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.backoff = time.Millisecond
+	return w
+}
+
+func TestWebhookRetries(t *testing.T) {
+	var requests atomic.Int32
+	var got objector.Match
+	w := testWebhook(t, func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer x" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request headers = %v, want the webhook headers and JSON", r.Header)
+		}
+		// The first two attempts fail
+		if requests.Add(1) < webhookAttempts {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding the delivered match failed: %v", err)
+		}
+	})
+	w.send(objector.Match{ID: "0123456789abcdef", Pattern: "JWT", Path: "window.token"})
+	w.close()
+	if n := requests.Load(); n != webhookAttempts {
+		t.Errorf("endpoint got %d requests, want %d", n, webhookAttempts)
+	}
+	if got.ID != "0123456789abcdef" || got.Path != "window.token" || w.failed != 0 {
+		t.Errorf("delivered %+v with %d failed, want the match delivered", got, w.failed)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	var requests atomic.Int32
+	w := testWebhook(t, func(rw http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+	w.send(objector.Match{Pattern: "JWT"})
+	w.send(objector.Match{Pattern: "JWT"})
+	w.close()
+	if n := requests.Load(); n != 2*webhookAttempts {
+		t.Errorf("endpoint got %d requests, want %d attempts for each match", n, webhookAttempts)
+	}
+	if w.failed != 2 {
+		t.Errorf("failed = %d, want both matches", w.failed)
+	}
+}
+
+func TestWebhookDropsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	started := make(chan struct{})
+	w := testWebhook(t, func(rw http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(started) })
+		<-release
+	})
+	// One match is being delivered and the queue fills behind it, so only
+	// the matches past that are dropped, without send blocking
+	w.send(objector.Match{Pattern: "JWT"})
+	<-started
+	for i := 0; i < webhookQueueSize+5; i++ {
+		w.send(objector.Match{Pattern: "JWT"})
+	}
+	if got := w.dropped.Load(); got != 5 {
+		t.Errorf("dropped = %d, want 5", got)
+	}
+	close(release)
+	w.close()
+	if w.failed != 0 {
+		t.Errorf("failed = %d, want the queued matches delivered", w.failed)
+	}
+}
@@ -0,0 +1,105 @@
+package objector
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadConfig reads a JSON configuration file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for i, p := range config.Patterns {
+		if p.Name == "" || p.Pattern == "" {
+			return nil, fmt.Errorf("parsing %s: pattern %d needs a name and a pattern", path, i+1)
+		}
+		if p.Severity != "" {
+			severity, err := ParseSeverity(string(p.Severity))
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: pattern %q: %w", path, p.Name, err)
+			}
+			config.Patterns[i].Severity = severity
+		}
+	}
+	return &config, nil
+}
+
+// LoadPatterns reads a pattern library, without the run settings of a
+// configuration file. A .csv file holds one name,regex,description line per
+// pattern, with an optional severity field and # comment lines; any other
+// file is a JSON array of patterns. Every pattern is compiled, so errors
+// name the line or index of the pattern at fault.
+func LoadPatterns(path string) ([]Pattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []Pattern
+	var where []string
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comment = '#'
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", path, err)
+			}
+			line, _ := reader.FieldPos(0)
+			if len(record) < 2 || len(record) > 4 {
+				return nil, fmt.Errorf("parsing %s: line %d: expected name,regex,description[,severity]", path, line)
+			}
+			p := Pattern{Name: record[0], Pattern: record[1]}
+			if len(record) > 2 {
+				p.Description = record[2]
+			}
+			if len(record) > 3 {
+				p.Severity = Severity(record[3])
+			}
+			patterns = append(patterns, p)
+			where = append(where, fmt.Sprintf("line %d", line))
+		}
+	} else {
+		if err := json.Unmarshal(data, &patterns); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		for i := range patterns {
+			where = append(where, fmt.Sprintf("pattern %d", i+1))
+		}
+	}
+
+	for i, p := range patterns {
+		if p.Name == "" || p.Pattern == "" {
+			return nil, fmt.Errorf("parsing %s: %s needs a name and a pattern", path, where[i])
+		}
+		if _, err := compilePattern(p.Pattern); err != nil {
+			return nil, fmt.Errorf("parsing %s: %s: pattern %q: %w", path, where[i], p.Name, err)
+		}
+		if p.Severity != "" {
+			severity, err := ParseSeverity(string(p.Severity))
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %s: pattern %q: %w", path, where[i], p.Name, err)
+			}
+			patterns[i].Severity = severity
+		}
+	}
+	return patterns, nil
+}
@@ -0,0 +1,63 @@
+package objector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+// consoleMessage formats the arguments of a console API call the way the
+// browser console would print them
+func consoleMessage(ev *runtime.EventConsoleAPICalled) string {
+	parts := make([]string, 0, len(ev.Args))
+	for _, arg := range ev.Args {
+		parts = append(parts, remoteObjectString(arg))
+	}
+
+	// Drop the CSS arguments consumed by %c directives
+	if len(parts) > 0 {
+		if styles := strings.Count(parts[0], "%c"); styles > 0 {
+			parts[0] = strings.ReplaceAll(parts[0], "%c", "")
+			if styles >= len(parts) {
+				styles = len(parts) - 1
+			}
+			parts = append(parts[:1], parts[1+styles:]...)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// exceptionMessage formats an uncaught exception thrown in the page
+func exceptionMessage(details *runtime.ExceptionDetails) string {
+	if details == nil {
+		return ""
+	}
+	message := details.Text
+	if details.Exception != nil && details.Exception.Description != "" {
+		message += " " + details.Exception.Description
+	}
+	if details.URL != "" {
+		message += fmt.Sprintf(" (%s:%d:%d)", details.URL, details.LineNumber+1, details.ColumnNumber+1)
+	}
+	return message
+}
+
+// remoteObjectString returns a printable representation of a remote object
+func remoteObjectString(arg *runtime.RemoteObject) string {
+	switch {
+	case arg.Type == runtime.TypeString:
+		var s string
+		if err := json.Unmarshal(arg.Value, &s); err == nil {
+			return s
+		}
+		return string(arg.Value)
+	case arg.Value != nil:
+		return string(arg.Value)
+	case arg.Description != "":
+		return arg.Description
+	default:
+		return arg.Type.String()
+	}
+}
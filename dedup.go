@@ -0,0 +1,73 @@
+package objector
+
+import "container/list"
+
+// dedupMap maps keys to values, such as the index of the match recording
+// each value. With a positive limit it only keeps the most recently seen
+// keys, so memory stays bounded on long scans at the cost of forgetting an
+// evicted key.
+type dedupMap[V any] struct {
+	limit int
+	keys  map[string]*list.Element
+	order *list.List
+}
+
+// dedupEntry is an element of the order of a dedupMap
+type dedupEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newDedupMap[V any](limit int) *dedupMap[V] {
+	return &dedupMap[V]{limit: limit, keys: make(map[string]*list.Element), order: list.New()}
+}
+
+// get returns the value of key and whether it is in the map, marking it as
+// recently seen
+func (m *dedupMap[V]) get(key string) (V, bool) {
+	e, ok := m.keys[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	m.order.MoveToFront(e)
+	return e.Value.(*dedupEntry[V]).value, true
+}
+
+// set maps key to value, evicting the least recently seen key when the map
+// is full
+func (m *dedupMap[V]) set(key string, value V) {
+	if e, ok := m.keys[key]; ok {
+		e.Value.(*dedupEntry[V]).value = value
+		m.order.MoveToFront(e)
+		return
+	}
+	m.keys[key] = m.order.PushFront(&dedupEntry[V]{key: key, value: value})
+	if m.limit > 0 && m.order.Len() > m.limit {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.keys, oldest.Value.(*dedupEntry[V]).key)
+	}
+}
+
+// dedupSet is a set of keys, such as the matches already reported, bounded
+// like a dedupMap, so an evicted key is reported again
+type dedupSet struct {
+	keys *dedupMap[struct{}]
+}
+
+func newDedupSet(limit int) *dedupSet {
+	return &dedupSet{keys: newDedupMap[struct{}](limit)}
+}
+
+// has reports whether key is in the set, marking it as recently seen
+func (s *dedupSet) has(key string) bool {
+	_, ok := s.keys.get(key)
+	return ok
+}
+
+// add puts key in the set, evicting the least recently seen key when the
+// set is full
+func (s *dedupSet) add(key string) {
+	s.keys.set(key, struct{}{})
+}
@@ -0,0 +1,37 @@
+package objector
+
+import "testing"
+
+func TestDedupMapEvictsLeastRecentlySeen(t *testing.T) {
+	m := newDedupMap[int](2)
+	m.set("a", 1)
+	m.set("b", 2)
+	// Seeing a makes b the least recently seen
+	if v, ok := m.get("a"); !ok || v != 1 {
+		t.Fatalf("get(a) = %d, %t, want 1, true", v, ok)
+	}
+	m.set("c", 3)
+	if _, ok := m.get("b"); ok {
+		t.Error("b is still in the map, want it evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := m.get(key); !ok || v != want {
+			t.Errorf("get(%s) = %d, %t, want %d, true", key, v, ok, want)
+		}
+	}
+	// Setting a known key replaces its value without evicting another
+	m.set("a", 4)
+	if v, _ := m.get("a"); v != 4 || m.order.Len() != 2 {
+		t.Errorf("get(a) = %d with %d keys, want 4 with 2", v, m.order.Len())
+	}
+}
+
+func TestDedupMapUnlimited(t *testing.T) {
+	m := newDedupMap[string](0)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.set(key, key)
+	}
+	if m.order.Len() != 4 {
+		t.Errorf("map has %d keys, want all 4 without a limit", m.order.Len())
+	}
+}
@@ -0,0 +1,31 @@
+package objector
+
+import (
+	"net/url"
+	"strings"
+)
+
+// domainAllowed reports whether the host of rawURL is one of domains or a
+// subdomain of one. An empty allowlist allows every host, and URLs without
+// a host, such as about:blank frames that inherit their parent's origin,
+// are always allowed.
+func domainAllowed(domains []string, rawURL string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return true
+	}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package objector
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+)
+
+// frameTracker records the main world execution context of every frame
+// in the page, so the monitor can be evaluated inside iframes. Frames
+// rendered out of process, usually cross-origin ones, never report a
+// context to the page's target and cannot be scanned.
+type frameTracker struct {
+	mu       sync.Mutex
+	contexts map[cdp.FrameID]runtime.ExecutionContextID
+	injected map[runtime.ExecutionContextID]bool
+	reported map[cdp.FrameID]bool
+}
+
+func newFrameTracker() *frameTracker {
+	return &frameTracker{
+		contexts: make(map[cdp.FrameID]runtime.ExecutionContextID),
+		injected: make(map[runtime.ExecutionContextID]bool),
+		reported: make(map[cdp.FrameID]bool),
+	}
+}
+
+// handle updates the tracked contexts from a target event
+func (t *frameTracker) handle(ev interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch ev := ev.(type) {
+	case *runtime.EventExecutionContextCreated:
+		var aux struct {
+			IsDefault bool        `json:"isDefault"`
+			FrameID   cdp.FrameID `json:"frameId"`
+		}
+		if err := json.Unmarshal(ev.Context.AuxData, &aux); err != nil || !aux.IsDefault || aux.FrameID == "" {
+			return
+		}
+		t.contexts[aux.FrameID] = ev.Context.ID
+	case *runtime.EventExecutionContextDestroyed:
+		for frameID, id := range t.contexts {
+			if id == ev.ExecutionContextID {
+				delete(t.contexts, frameID)
+			}
+		}
+		delete(t.injected, ev.ExecutionContextID)
+	case *runtime.EventExecutionContextsCleared:
+		t.contexts = make(map[cdp.FrameID]runtime.ExecutionContextID)
+		t.injected = make(map[runtime.ExecutionContextID]bool)
+	}
+}
+
+// frame is a child frame of the page that can be evaluated in
+type frame struct {
+	url       string
+	contextID runtime.ExecutionContextID
+	// inject is set the first time the frame's context is returned
+	inject bool
+}
+
+// childFrames lists the accessible frames below the top frame. Frames
+// without a context are passed to blocked once each.
+func (t *frameTracker) childFrames(ctx context.Context, blocked func(url string)) ([]frame, error) {
+	tree, err := page.GetFrameTree().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*cdp.Frame
+	var walk func(tree *page.FrameTree)
+	walk = func(tree *page.FrameTree) {
+		for _, child := range tree.ChildFrames {
+			all = append(all, child.Frame)
+			walk(child)
+		}
+	}
+	walk(tree)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var frames []frame
+	for _, f := range all {
+		id, ok := t.contexts[f.ID]
+		if !ok {
+			if !t.reported[f.ID] {
+				t.reported[f.ID] = true
+				blocked(f.URL)
+			}
+			continue
+		}
+		frames = append(frames, frame{url: f.URL, contextID: id, inject: !t.injected[id]})
+		t.injected[id] = true
+	}
+	return frames, nil
+}
+
+// framePath prefixes an object path with the URL of the frame it was
+// found in
+func framePath(url, path string) string {
+	return "frame(" + url + "):" + path
+}
+
+// evaluateIn runs script in the given execution context and returns its
+// string result
+func evaluateIn(ctx context.Context, contextID runtime.ExecutionContextID, script string) (string, error) {
+	res, exception, err := runtime.Evaluate(script).
+		WithContextID(contextID).
+		WithReturnByValue(true).
+		Do(ctx)
+	if err != nil {
+		return "", err
+	}
+	if exception != nil {
+		return "", exception
+	}
+	var result string
+	if len(res.Value) > 0 {
+		if err := json.Unmarshal(res.Value, &result); err != nil {
+			return "", err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,419 @@
+package objector
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultHARBodySize is the number of bytes of each response body kept in
+// a HAR when HAR.MaxBodySize is not set
+const DefaultHARBodySize = 1 << 20
+
+// HAR collects the network traffic of one or more scans as a HAR 1.2 log.
+// Each scanned URL is a page of the log.
+type HAR struct {
+	// MaxBodySize caps the bytes of each response body kept (default:
+	// DefaultHARBodySize, negative to leave bodies out). Truncated bodies
+	// are noted in the entry's content comment.
+	MaxBodySize int
+
+	mu  sync.Mutex
+	log harLog
+}
+
+// NewHAR returns an empty log created by the given objector version
+func NewHAR(version string) *HAR {
+	return &HAR{log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "objector", Version: version},
+		Pages:   []harPage{},
+		Entries: []*harEntry{},
+	}}
+}
+
+// WriteFile writes the log to path as a .har document
+func (h *HAR) WriteFile(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sort.SliceStable(h.log.Entries, func(i, j int) bool {
+		return h.log.Entries[i].started.Before(h.log.Entries[j].started)
+	})
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(struct {
+		Log harLog `json:"log"`
+	}{h.log}); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Pages   []harPage   `json:"pages"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harPage struct {
+	StartedDateTime string `json:"startedDateTime"`
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	PageTimings     struct {
+		OnContentLoad float64 `json:"onContentLoad"`
+		OnLoad        float64 `json:"onLoad"`
+	} `json:"pageTimings"`
+}
+
+type harEntry struct {
+	Pageref         string      `json:"pageref"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+
+	started time.Time
+	// timing and sent mark the request in monotonic seconds, for the
+	// receive phase measured when loading finishes
+	timing *network.ResourceTiming
+	sent   float64
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []struct{}     `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []struct{}     `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// harTimings are in milliseconds, -1 for phases that did not apply
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harRecorder adds the traffic of one scanned page to a HAR
+type harRecorder struct {
+	har     *HAR
+	pageref string
+	entries map[network.RequestID]*harEntry
+
+	// pending counts body fetches in flight, none start once closed
+	pending sync.WaitGroup
+	closed  bool
+}
+
+// page starts a new page for url and returns its recorder
+func (h *HAR) page(url string) *harRecorder {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p := harPage{
+		StartedDateTime: time.Now().Format(time.RFC3339Nano),
+		ID:              fmt.Sprintf("page_%d", len(h.log.Pages)+1),
+		Title:           url,
+	}
+	h.log.Pages = append(h.log.Pages, p)
+	return &harRecorder{har: h, pageref: p.ID, entries: make(map[network.RequestID]*harEntry)}
+}
+
+// handle records a network event. It returns true when the request has
+// finished loading and its body should be passed to fetchBody.
+func (r *harRecorder) handle(ev interface{}) (network.RequestID, bool) {
+	r.har.mu.Lock()
+	defer r.har.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if strings.HasPrefix(ev.Request.URL, "data:") {
+			return "", false
+		}
+		// A redirect reuses the request id, completing the previous entry
+		if entry := r.entries[ev.RequestID]; entry != nil && ev.RedirectResponse != nil {
+			entry.setResponse(ev.RedirectResponse)
+			entry.Response.RedirectURL = ev.Request.URL
+			entry.finish(ev.Timestamp)
+		}
+		entry := &harEntry{
+			Pageref: r.pageref,
+			started: time.Now(),
+			Request: harRequest{
+				Method:      ev.Request.Method,
+				URL:         ev.Request.URL,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []struct{}{},
+				Headers:     harHeaders(ev.Request.Headers),
+				QueryString: harQuery(ev.Request.URL),
+				HeadersSize: -1,
+			},
+			Response: harResponse{
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []struct{}{},
+				Headers:     []harNameValue{},
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Timings: harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+		}
+		if ev.WallTime != nil {
+			entry.started = ev.WallTime.Time()
+		}
+		entry.StartedDateTime = entry.started.Format(time.RFC3339Nano)
+		if ev.Timestamp != nil {
+			entry.sent = monotonicSeconds(ev.Timestamp)
+		}
+		if ev.Request.HasPostData {
+			entry.Request.PostData = &harPostData{
+				MimeType: headerValue(ev.Request.Headers, "Content-Type"),
+				Text:     ev.Request.PostData,
+			}
+			entry.Request.BodySize = len(ev.Request.PostData)
+		}
+		r.entries[ev.RequestID] = entry
+		r.har.log.Entries = append(r.har.log.Entries, entry)
+
+	case *network.EventResponseReceived:
+		if entry := r.entries[ev.RequestID]; entry != nil {
+			entry.setResponse(ev.Response)
+		}
+
+	case *network.EventLoadingFinished:
+		entry := r.entries[ev.RequestID]
+		if entry == nil {
+			return "", false
+		}
+		entry.Response.BodySize = int64(ev.EncodedDataLength)
+		entry.finish(ev.Timestamp)
+		if r.closed || r.har.MaxBodySize < 0 {
+			return "", false
+		}
+		r.pending.Add(1)
+		return ev.RequestID, true
+
+	case *network.EventLoadingFailed:
+		if entry := r.entries[ev.RequestID]; entry != nil {
+			entry.Comment = ev.ErrorText
+			if ev.Canceled {
+				entry.Comment = "canceled"
+			}
+			entry.finish(ev.Timestamp)
+		}
+	}
+	return "", false
+}
+
+// fetchBody stores the body of a finished request, capped at MaxBodySize
+func (r *harRecorder) fetchBody(ctx context.Context, id network.RequestID) error {
+	defer r.pending.Done()
+	var body []byte
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		body, err = network.GetResponseBody(id).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return err
+	}
+
+	r.har.mu.Lock()
+	defer r.har.mu.Unlock()
+	entry := r.entries[id]
+	if entry == nil {
+		return nil
+	}
+	limit := r.har.MaxBodySize
+	if limit == 0 {
+		limit = DefaultHARBodySize
+	}
+	content := &entry.Response.Content
+	content.Size = len(body)
+	if len(body) > limit {
+		body = body[:limit]
+		content.Comment = fmt.Sprintf("body truncated to %d of %d bytes", limit, content.Size)
+	}
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return nil
+}
+
+// wait stops fetching bodies and waits for the fetches in flight
+func (r *harRecorder) wait() {
+	r.har.mu.Lock()
+	r.closed = true
+	r.har.mu.Unlock()
+	r.pending.Wait()
+}
+
+// setResponse fills the response of the entry
+func (e *harEntry) setResponse(resp *network.Response) {
+	e.Response.Status = resp.Status
+	e.Response.StatusText = resp.StatusText
+	e.Response.Headers = harHeaders(resp.Headers)
+	e.Response.Content.MimeType = resp.MimeType
+	e.Response.RedirectURL = headerValue(resp.Headers, "Location")
+	if resp.Protocol != "" {
+		e.Response.HTTPVersion = strings.ToUpper(resp.Protocol)
+		e.Request.HTTPVersion = e.Response.HTTPVersion
+	}
+	e.ServerIPAddress = resp.RemoteIPAddress
+	e.timing = resp.Timing
+	if t := resp.Timing; t != nil {
+		e.Timings.Blocked = firstPositive(t.DNSStart, t.ConnectStart, t.SendStart)
+		e.Timings.DNS = phase(t.DNSStart, t.DNSEnd)
+		e.Timings.Connect = phase(t.ConnectStart, t.ConnectEnd)
+		e.Timings.SSL = phase(t.SslStart, t.SslEnd)
+		e.Timings.Send = max(phase(t.SendStart, t.SendEnd), 0)
+		e.Timings.Wait = max(t.ReceiveHeadersEnd-t.SendEnd, 0)
+	}
+}
+
+// finish sets the receive phase and total time once the response has
+// been read
+func (e *harEntry) finish(timestamp *cdp.MonotonicTime) {
+	if timestamp == nil {
+		return
+	}
+	end := monotonicSeconds(timestamp)
+	if t := e.timing; t != nil {
+		e.Timings.Receive = max((end-t.RequestTime)*1000-t.ReceiveHeadersEnd, 0)
+	}
+	if e.sent > 0 {
+		e.Time = max((end-e.sent)*1000, 0)
+	}
+}
+
+// monotonicSeconds converts a protocol timestamp back to seconds, the unit
+// of ResourceTiming.RequestTime
+func monotonicSeconds(t *cdp.MonotonicTime) float64 {
+	return t.Time().Sub(*cdp.MonotonicTimeEpoch).Seconds()
+}
+
+// phase is the duration between two timing offsets, -1 when not measured
+func phase(start, end float64) float64 {
+	if start < 0 || end < 0 {
+		return -1
+	}
+	return end - start
+}
+
+func firstPositive(offsets ...float64) float64 {
+	for _, offset := range offsets {
+		if offset >= 0 {
+			return offset
+		}
+	}
+	return -1
+}
+
+// harHeaders converts protocol headers to a sorted name/value list
+func harHeaders(headers network.Headers) []harNameValue {
+	list := []harNameValue{}
+	for name, value := range headers {
+		// Repeated headers arrive joined by newlines
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			list = append(list, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// headerValue looks up a header case-insensitively
+func headerValue(headers network.Headers, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// harQuery lists the query parameters of rawURL
+func harQuery(rawURL string) []harNameValue {
+	list := []harNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return list
+	}
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		list = append(list, harNameValue{Name: name, Value: value})
+	}
+	return list
+}
@@ -0,0 +1,168 @@
+package objector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// headerSessions sends the headers from the targets that have a session of
+// their own, which setHeaders on the page does not reach: frames Chrome
+// renders out of process and the popups the page opens. Each is paused as
+// it attaches until its session has the headers, so not even its first
+// request goes without them.
+type headerSessions struct {
+	headers map[string]string
+	// pausePopups is set when the browser is ours. A shared browser is
+	// left running the tabs other clients open.
+	pausePopups bool
+	logger      *slog.Logger
+
+	mu sync.Mutex
+	// pages are the page and its popups, whose own popups are ours
+	pages map[target.ID]bool
+}
+
+func newHeaderSessions(headers map[string]string, pausePopups bool, logger *slog.Logger) *headerSessions {
+	return &headerSessions{
+		headers:     headers,
+		pausePopups: pausePopups,
+		logger:      logger,
+		pages:       make(map[target.ID]bool),
+	}
+}
+
+// enable attaches to the frames and popups of the page at ctx from now on
+func (h *headerSessions) enable(ctx context.Context) error {
+	c := chromedp.FromContext(ctx)
+	h.mu.Lock()
+	h.pages[c.Target.TargetID] = true
+	h.mu.Unlock()
+	if err := pauseFrames(ctx); err != nil {
+		return err
+	}
+	// Popups attach to the browser, which only has flat sessions
+	return target.SetAutoAttach(true, h.pausePopups).
+		WithFlatten(true).
+		WithFilter(target.Filter{{Type: "page"}}).
+		Do(cdp.WithExecutor(ctx, c.Browser))
+}
+
+// pauseFrames has the frames of the page at ctx that run out of process
+// attach paused. Their sessions are not flattened: chromedp only reads the
+// sessions it creates, while messages of the others come through the page.
+func pauseFrames(ctx context.Context) error {
+	return target.SetAutoAttach(true, true).Do(ctx)
+}
+
+// handleTarget is the target listener of a page, giving its frames the
+// headers as they attach. ctx is the page's.
+func (h *headerSessions) handleTarget(ctx context.Context, ev interface{}) {
+	attached, ok := ev.(*target.EventAttachedToTarget)
+	if !ok || attached.TargetInfo.Type != "iframe" || !attached.WaitingForDebugger {
+		return
+	}
+	c := chromedp.FromContext(ctx)
+	go h.sendFrameHeaders(cdp.WithExecutor(ctx, c.Target), attached.SessionID)
+}
+
+// sendFrameHeaders sets the headers in the paused session of a frame and
+// resumes it. The frame is resumed even when the headers fail, a frame
+// without them is better than one that never loads.
+func (h *headerSessions) sendFrameHeaders(ctx context.Context, session target.SessionID) {
+	messages := []sessionMessage{
+		{ID: 1, Method: "Network.enable"},
+		{ID: 2, Method: "Network.setExtraHTTPHeaders", Params: map[string]interface{}{"headers": h.headers}},
+		{ID: 3, Method: "Runtime.runIfWaitingForDebugger"},
+	}
+	for _, msg := range messages {
+		if err := msg.send(ctx, session); err != nil {
+			h.logger.Debug("could not send the headers to a frame", "method", msg.Method, "error", err)
+		}
+	}
+}
+
+// handleBrowser is the browser listener of the scan, giving the headers to
+// the popups of the page as they attach. browserCtx is the page's context
+// before any timeout, so a popup lasts as long as the browser.
+func (h *headerSessions) handleBrowser(browserCtx context.Context, ev interface{}) {
+	attached, ok := ev.(*target.EventAttachedToTarget)
+	if !ok || attached.TargetInfo.Type != "page" {
+		return
+	}
+	id := attached.TargetInfo.TargetID
+	h.mu.Lock()
+	// Attaching a session of our own is reported too. In a browser of our
+	// own every paused page is a popup of the scan.
+	popup := !h.pages[id] && (h.pages[attached.TargetInfo.OpenerID] || attached.WaitingForDebugger)
+	if popup {
+		h.pages[id] = true
+	}
+	h.mu.Unlock()
+	if popup {
+		go h.attachPopup(browserCtx, id, attached.WaitingForDebugger)
+	}
+}
+
+// attachPopup sets the headers in a session of its own on the popup id,
+// along with those of its frames, and resumes it if paused. The session is
+// never cancelled, as that closes the popup; it ends with the browser.
+func (h *headerSessions) attachPopup(browserCtx context.Context, id target.ID, paused bool) {
+	ctx, _ := chromedp.NewContext(browserCtx, chromedp.WithTargetID(id))
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		h.handleTarget(ctx, ev)
+	})
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := setHeaders(ctx, h.headers); err != nil {
+			return err
+		}
+		return pauseFrames(ctx)
+	}))
+	if err != nil {
+		h.logger.Debug("could not send the headers to a popup", "target", id, "error", err)
+	}
+	if paused {
+		if err := chromedp.Run(ctx, runtime.RunIfWaitingForDebugger()); err != nil {
+			h.logger.Debug("could not resume a popup", "target", id, "error", err)
+		}
+	}
+}
+
+// sessionMessage is a DevTools command sent into a session that is not
+// flattened
+type sessionMessage struct {
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// send delivers the message to session through the target at ctx, without
+// waiting for the answer
+func (m sessionMessage) send(ctx context.Context, session target.SessionID) error {
+	message, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return cdp.Execute(ctx, "Target.sendMessageToTarget", sendMessageParams{Message: string(message), SessionID: session}, nil)
+}
+
+// sendMessageParams are the parameters of Target.sendMessageToTarget, which
+// cdproto dropped as deprecated, though it is the only way into sessions
+// that are not flattened
+type sendMessageParams struct {
+	Message   string           `json:"message"`
+	SessionID target.SessionID `json:"sessionId"`
+}
+
+// MarshalEasyJSON satisfies easyjson.Marshaler
+func (p sendMessageParams) MarshalEasyJSON(w *jwriter.Writer) {
+	b, err := json.Marshal(p)
+	w.Raw(b, err)
+}
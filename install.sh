@@ -29,7 +29,7 @@ go mod download
 
 # Build the application
 echo "Building application..."
-go build -o objector
+go build -o objector ./cmd/objector
 
 # Install the binary
 echo "Installing binary..."
@@ -0,0 +1,45 @@
+package objector
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out the requests scans make to their targets: page loads,
+// including retries, and the actions run after a page loads. One Limiter
+// can be shared by several scans.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter returns a limiter allowing one request per interval
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{interval: interval}
+}
+
+// wait blocks until the next request is allowed. A nil limiter never
+// blocks.
+func (l *Limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	at := l.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
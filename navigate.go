@@ -0,0 +1,94 @@
+package objector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// ErrCertificate is returned when a page cannot be loaded because its TLS
+// certificate is not trusted, such as a self-signed one. Options.Insecure
+// ignores certificate errors.
+var ErrCertificate = errors.New("TLS certificate is not trusted")
+
+// setHeaders sends headers with every request the page makes from now on.
+// They belong to the target's network session, so client-side navigations
+// and same-process frames carry them too. headerSessions covers the targets
+// with sessions of their own.
+func setHeaders(ctx context.Context, headers map[string]string) error {
+	extra := make(network.Headers, len(headers))
+	for k, v := range headers {
+		extra[k] = v
+	}
+	return network.SetExtraHTTPHeaders(extra).Do(ctx)
+}
+
+// setContent replaces the document of the blank page the browser starts on
+// with html
+func setContent(ctx context.Context, html string) error {
+	tree, err := page.GetFrameTree().Do(ctx)
+	if err != nil {
+		return err
+	}
+	return page.SetDocumentContent(tree.Frame.ID, html).Do(ctx)
+}
+
+// navigate loads url with headers, retrying failed navigations and server
+// errors up to retries times with exponential backoff. A server error that
+// persists after the last attempt is not a failure, the error page is
+// scanned like any other. Retries also wait for limiter. Local file:// URLs
+// are loaded once, they have no server to send headers to or retry.
+func navigate(ctx context.Context, url string, headers map[string]string, retries int, limiter *Limiter, logger *slog.Logger) error {
+	if strings.HasPrefix(url, "file:") {
+		return chromedp.Navigate(url).Do(ctx)
+	}
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := limiter.wait(ctx); err != nil {
+				return err
+			}
+		}
+		// Set the headers before every attempt, so a navigation never
+		// depends on state left by an earlier one
+		if err := setHeaders(ctx, headers); err != nil {
+			return err
+		}
+		resp, err := chromedp.RunResponse(ctx, chromedp.Navigate(url))
+		if err == nil && (resp == nil || resp.Status < 500) {
+			return nil
+		}
+		// Retrying cannot fix a certificate
+		if err != nil && strings.Contains(err.Error(), "net::ERR_CERT_") {
+			return fmt.Errorf("%w: %v", ErrCertificate, err)
+		}
+		// Nor a redirect stopped by NoFollowRedirects
+		if err != nil && strings.Contains(err.Error(), "net::ERR_BLOCKED_BY_CLIENT") {
+			return err
+		}
+		if attempt >= retries || ctx.Err() != nil {
+			if err == nil {
+				logger.Warn("server error persists, scanning anyway", "url", url, "status", resp.Status, "attempts", attempt+1)
+			}
+			return err
+		}
+		if err == nil {
+			err = fmt.Errorf("server responded with %d %s", resp.Status, resp.StatusText)
+		}
+		logger.Warn("navigation failed, retrying", "url", url, "attempt", attempt+1, "attempts", retries+1, "error", err, "backoff", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
@@ -1,30 +1,26 @@
-package main
+// Package objector monitors the JavaScript object graph of a web page for
+// exposed credentials.
+package objector
 
 import (
-	"context"
-	"encoding/json"
-	"flag"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
-
-	"github.com/chromedp/cdproto/network"
-	"github.com/chromedp/chromedp"
+	"unicode"
+	"unicode/utf16"
 )
 
-func init() {
-	// Redirect all logging to /dev/null
-	log.SetOutput(ioutil.Discard)
-}
-
 // Pattern represents a pattern configuration
 type Pattern struct {
-	Name        string `json:"name"`
-	Pattern     string `json:"pattern"`
-	Description string `json:"description"`
+	Name        string   `json:"name"`
+	Pattern     string   `json:"pattern"`
+	Description string   `json:"description"`
+	Severity    Severity `json:"severity,omitempty"`
 }
 
 // Config represents the configuration file structure
@@ -32,970 +28,458 @@ type Config struct {
 	Patterns     []Pattern `json:"patterns"`
 	IgnoredPaths []string  `json:"ignoredPaths"`
 	MaxDepth     int       `json:"maxDepth"`
+	// Options sets flags of the objector command by name, such as "timeout"
+	// or "format", so a scan can be reproduced from the file alone
+	Options map[string]interface{} `json:"options,omitempty"`
 }
 
 // Match represents a detected pattern match
 type Match struct {
+	// ID identifies the match across runs, see MatchID
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
 	Pattern     string    `json:"pattern"`
 	Path        string    `json:"path"`
+	Paths       []string  `json:"paths,omitempty"`
 	Value       string    `json:"value"`
+	Captured    string    `json:"captured,omitempty"`
 	Description string    `json:"description"`
+	Severity    Severity  `json:"severity"`
+	Truncated   bool      `json:"truncated,omitempty"`
 	Timestamp   time.Time `json:"timestamp"`
+	Screenshot  string    `json:"screenshot,omitempty"`
+	// Context is only set with Options.ContextChars
+	Context *MatchContext `json:"context,omitempty"`
+	// Verified, if set, tells whether the provider accepted the secret
+	// when it was checked, which the scanner itself never does
+	Verified *bool `json:"verified,omitempty"`
+	// Count is the number of times the match was seen, once for each pass
+	// that found it and each time the monitor intercepted it, and LastSeen
+	// the last of those times. Timestamp is the first. Matches passed to
+	// Options.OnMatch are seen once so far.
+	Count    int        `json:"count"`
+	LastSeen *time.Time `json:"lastSeen,omitempty"`
+	// Previous is the value this one replaced at the path, only set with
+	// Options.EmitOnChange
+	Previous string `json:"previous,omitempty"`
+}
+
+// MatchID returns the ID of a match of pattern at path with value, as
+// reported before any redaction: the first 16 hex digits of the SHA-256 of
+// the three joined by NUL bytes. The URL is left out, like in a Baseline,
+// so the same finding has the same ID in every environment.
+func MatchID(pattern, path, value string) string {
+	sum := sha256.Sum256([]byte(pattern + "\x00" + path + "\x00" + value))
+	return hex.EncodeToString(sum[:8])
 }
 
+// MatchContext is what surrounds a match, to help tell a secret from a
+// false positive. It is only reported with Options.ContextChars.
+type MatchContext struct {
+	// Before and After are the text of the value on either side of the
+	// matched text, so they are empty when the whole value matched
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// Siblings names the other properties of the object holding the value,
+	// when it was found by walking the object graph
+	Siblings []string `json:"siblings,omitempty"`
+}
+
+// Default limits for a single pass over the object graph
+const (
+	DefaultScanBudget     = 100000
+	DefaultScanTimeBudget = time.Second
+)
+
+// DefaultJSMatchCap is the number of matches the injected scripts hold in
+// the page at most
+const DefaultJSMatchCap = 10000
+
+// DefaultMaxValueSize is the number of bytes of a matched value that are
+// reported, longer values are still matched in full
+const DefaultMaxValueSize = 4096
+
+// Time between passes over the object graph
+const (
+	DefaultScanInterval = time.Second
+	// MinScanInterval keeps back to back passes from pinning the CPU
+	MinScanInterval = 100 * time.Millisecond
+)
+
 // ObjectMonitor represents the monitoring functionality
 type ObjectMonitor struct {
-	patterns     map[string]struct{ pattern, description string }
+	patterns     map[string]monitoredPattern
+	custom       []customSearch
 	ignoredPaths map[string]bool
+	excludePaths []string
+	contextChars int
 	maxDepth     int
-	foundMatches map[string]bool
+	foundMatches *dedupSet
 	debug        bool
+	color        bool
+	scanStorage  bool
+	scanDOM      bool
+	disabled     []string
+	deepScan     bool
+	decodeBase64 bool
+	objectBudget int
+	timeBudget   time.Duration
+	interval     time.Duration
+	minValueLen  int
+	maxValueLen  int
+	maxValueSize int
+	matchCap     int
 	stats        struct {
 		objectsScanned int
 		matchesFound   int
 	}
 }
 
+// monitoredPattern is a pattern as it is handed to the injected scripts,
+// along with its Go compilation used to re-check what the scripts report
+type monitoredPattern struct {
+	pattern     string
+	description string
+	severity    Severity
+	re          *regexp.Regexp
+}
+
+// customSearch is a user supplied search that replaces the patterns
+type customSearch struct {
+	name string
+	monitoredPattern
+}
+
+// mustPattern compiles a built-in pattern
+func mustPattern(pattern, description string, severity Severity) monitoredPattern {
+	return monitoredPattern{
+		pattern:     pattern,
+		description: description,
+		severity:    severity,
+		re:          regexp.MustCompile(pattern),
+	}
+}
+
 // NewObjectMonitor creates a new ObjectMonitor instance
 func NewObjectMonitor() *ObjectMonitor {
 	// Initialize with hardcoded patterns
-	patterns := make(map[string]struct{ pattern, description string })
+	patterns := make(map[string]monitoredPattern)
 
 	// Add default patterns
-	patterns["AWS Access Key"] = struct{ pattern, description string }{
-		pattern:     `\b(AKIA|ASIA)[A-Z0-9]{16}\b`,
-		description: "AWS Access Key ID",
-	}
-	patterns["AWS Secret Key"] = struct{ pattern, description string }{
-		pattern:     `\b[0-9a-zA-Z/+]{40}\b`,
-		description: "AWS Secret Access Key",
-	}
-	patterns["Private Key"] = struct{ pattern, description string }{
-		pattern:     `-----BEGIN (RSA|DSA|EC|OPENSSH) PRIVATE KEY-----`,
-		description: "Private Key File",
-	}
-	patterns["API Key"] = struct{ pattern, description string }{
-		pattern:     `\b[a-zA-Z0-9]{32,}\b`,
-		description: "Generic API Key",
-	}
-	patterns["JWT Token"] = struct{ pattern, description string }{
-		pattern:     `\bey[A-Za-z0-9-_=]+\.[A-Za-z0-9-_=]+\.?[A-Za-z0-9-_.+/=]*\b`,
-		description: "JWT Token",
-	}
+	patterns["AWS Access Key"] = mustPattern(
+		`AKIA[A-Z0-9]{16}`,
+		"AWS Access Key ID",
+		SeverityCritical,
+	)
+	patterns["AWS Secret Key"] = mustPattern(
+		`^[A-Za-z0-9/+]{40}$|(?:[Ss]ecret_?[Aa]ccess_?[Kk]ey|SECRET_ACCESS_KEY|[Ss]ecret_?[Kk]ey|SECRET_KEY)["']?\s*[:=]\s*["']?[A-Za-z0-9/+]{40}(?:[^A-Za-z0-9/+=]|$)`,
+		"AWS Secret Access Key",
+		SeverityCritical,
+	)
+	patterns["Private Key"] = mustPattern(
+		`-----BEGIN (?:RSA|OPENSSH|DSA|EC|PGP) PRIVATE KEY-----`,
+		"Private Key Header",
+		SeverityCritical,
+	)
+	patterns["JWT Token"] = mustPattern(
+		`eyJ[A-Za-z0-9-_=]+\.[A-Za-z0-9-_=]+\.?[A-Za-z0-9-_.+/=]*$`,
+		"JWT Token",
+		SeverityMedium,
+	)
+	patterns["GitHub Token"] = mustPattern(
+		`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b`,
+		"GitHub Personal Access, OAuth or App Token",
+		SeverityHigh,
+	)
+	patterns["GitHub Fine-Grained Token"] = mustPattern(
+		`\bgithub_pat_[A-Za-z0-9_]{82}\b`,
+		"GitHub Fine-Grained Personal Access Token",
+		SeverityHigh,
+	)
+	patterns["GitLab Token"] = mustPattern(
+		`\bglpat-[A-Za-z0-9_-]{20}`,
+		"GitLab Personal Access Token",
+		SeverityHigh,
+	)
+	patterns["Slack Token"] = mustPattern(
+		`\bxox[baprs]-[A-Za-z0-9-]{10,}`,
+		"Slack Bot, App, User or Refresh Token",
+		SeverityHigh,
+	)
+	patterns["Stripe Secret Key"] = mustPattern(
+		`\b(?:sk|rk)_live_[A-Za-z0-9]{24,}`,
+		"Stripe Live Secret or Restricted Key",
+		SeverityCritical,
+	)
+	patterns["Stripe Publishable Key"] = mustPattern(
+		`\bpk_live_[A-Za-z0-9]{24,}`,
+		"Stripe Live Publishable Key",
+		SeverityLow,
+	)
+	patterns["Google API Key"] = mustPattern(
+		`\bAIza[0-9A-Za-z_-]{35}`,
+		"Google API Key",
+		SeverityMedium,
+	)
+	patterns["Twilio SID"] = mustPattern(
+		`\b(?:AC|SK)[0-9a-f]{32}\b`,
+		"Twilio Account or API Key SID",
+		SeverityLow,
+	)
 
 	// Default ignored paths
 	ignoredPaths := map[string]bool{
-		"performance":       true,
-		"localStorage":      true,
-		"sessionStorage":    true,
-		"indexedDB":         true,
-		"webkitStorageInfo": true,
-		"chrome":            true,
-		"document":          true,
-		"history":           true,
+		"window.performance":       true,
+		"window.localStorage":      true,
+		"window.sessionStorage":    true,
+		"window.indexedDB":         true,
+		"window.webkitStorageInfo": true,
+		"window.chrome":            true,
+		"window.document":          true,
+		"window.history":           true,
 	}
 
 	return &ObjectMonitor{
 		patterns:     patterns,
 		ignoredPaths: ignoredPaths,
-		maxDepth:     10,
-		foundMatches: make(map[string]bool),
+		maxDepth:     5,
+		objectBudget: DefaultScanBudget,
+		timeBudget:   DefaultScanTimeBudget,
+		interval:     DefaultScanInterval,
+		maxValueSize: DefaultMaxValueSize,
+		matchCap:     DefaultJSMatchCap,
+		foundMatches: newDedupSet(0),
 		debug:        false,
+		color:        true,
 	}
 }
 
-// AddPattern adds a new pattern to monitor
-func (m *ObjectMonitor) AddPattern(name, pattern, description string) {
-	m.patterns[name] = struct{ pattern, description string }{
+// AddPattern adds a new pattern to monitor. The pattern must compile as a
+// Go regular expression without Go-only syntax such as (?i) or \z, so it
+// means the same in JavaScript, and not nest quantifiers that backtrack
+// catastrophically.
+func (m *ObjectMonitor) AddPattern(name, pattern, description string) error {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return fmt.Errorf("pattern %q: %w", name, err)
+	}
+	m.patterns[name] = monitoredPattern{
 		pattern:     pattern,
 		description: description,
+		severity:    DefaultSeverity,
+		re:          re,
 	}
+	return nil
 }
 
-// LogMatch handles a detected match
-func (m *ObjectMonitor) LogMatch(match Match) {
-	// Print match in a clean format
-	fmt.Printf("\033[31m[ObjectMonitor Match]\033[0m\n")
-	fmt.Printf("Timestamp:   %s\n", match.Timestamp.Format("2006-01-02 15:04:05"))
-	fmt.Printf("Pattern:     %s\n", match.Pattern)
-	fmt.Printf("Path:        %s\n", match.Path)
-	fmt.Printf("Value:       %s\n", match.Value)
-	fmt.Printf("Description: %s\n\n", match.Description)
+// SetSeverity changes the severity of the named pattern
+func (m *ObjectMonitor) SetSeverity(name string, severity Severity) error {
+	p, ok := m.patterns[name]
+	if !ok {
+		return fmt.Errorf("unknown pattern %q", name)
+	}
+	if severity.Rank() == 0 {
+		return fmt.Errorf("pattern %q: unknown severity %q", name, severity)
+	}
+	p.severity = severity
+	m.patterns[name] = p
+	return nil
 }
 
-// GetMonitoringScript returns the JavaScript code for monitoring
-func (m *ObjectMonitor) GetMonitoringScript() string {
-	return `
-		class ObjectMonitor {
-			constructor(options = {}) {
-				this.patterns = new Map();
-				this.ignoredPaths = new Set(options.ignoredPaths || [
-					'performance', 'localStorage', 'sessionStorage', 'indexedDB',
-					'webkitStorageInfo', 'chrome', 'document', 'history'
-				]);
-				this.maxDepth = options.maxDepth || 10;
-				this.foundMatches = new Set();
-				this.debug = false;
-				this.scanInterval = null;
-				this.stats = {
-					objectsScanned: 0,
-					matchesFound: 0
-				};
-			}
-
-			addPattern(name, pattern, description = '') {
-				if (!(pattern instanceof RegExp)) {
-					pattern = new RegExp(pattern);
-				}
-				this.patterns.set(name, { pattern, description });
-				return this;
-			}
-
-			checkValue(value, path) {
-				if (typeof value !== 'string') return;
-				
-				for (const [name, { pattern, description }] of this.patterns) {
-					const matches = value.match(pattern);
-					if (matches) {
-						const match = {
-							pattern: name,
-							path,
-							value,
-							matches,
-							description,
-							timestamp: new Date().toISOString()
-						};
-						
-						const matchKey = path + ':' + value;
-						if (!this.foundMatches.has(matchKey)) {
-							this.foundMatches.add(matchKey);
-							this.logMatch(match);
-						}
-					}
-				}
-			}
-
-			logMatch(match) {
-				const output = {
-					timestamp: match.timestamp,
-					pattern: match.pattern,
-					path: match.path,
-					value: match.value,
-					description: match.description
-				};
-
-				console.log('%c[ObjectMonitor Match]', 'color: #ff0000; font-weight: bold');
-				console.table([output]);
-				
-				const event = new CustomEvent('objectMonitorMatch', { detail: match });
-				window.dispatchEvent(event);
-			}
-
-			start() {
-				if (!window.__objectMonitorActive) {
-					try {
-						this.scanObject(window, 'window');
-						window.__objectMonitorActive = true;
-
-						const originalString = String;
-						window.String = function(value) {
-							const str = originalString(value);
-							monitor.checkValue(str, 'String constructor');
-							return str;
-						};
-						window.String.prototype = originalString.prototype;
-
-						const originalDefineProperty = Object.defineProperty;
-						Object.defineProperty = function(obj, prop, descriptor) {
-							if (descriptor && descriptor.value) {
-								if (typeof descriptor.value === 'string') {
-									monitor.checkValue(descriptor.value, obj.constructor ? obj.constructor.name + '.' + prop : 'Object.' + prop);
-								}
-							}
-							return originalDefineProperty.call(this, obj, prop, descriptor);
-						};
-
-						const originalDefineProperties = Object.defineProperties;
-						Object.defineProperties = function(obj, props) {
-							for (const [prop, descriptor] of Object.entries(props)) {
-								if (descriptor && descriptor.value) {
-									if (typeof descriptor.value === 'string') {
-										monitor.checkValue(descriptor.value, obj.constructor ? obj.constructor.name + '.' + prop : 'Object.' + prop);
-									}
-								}
-							}
-							return originalDefineProperties.call(this, obj, props);
-						};
-
-						const originalCreate = Object.create;
-						Object.create = function(proto, properties) {
-							const obj = originalCreate.call(this, proto, properties);
-							if (properties) {
-								for (const [prop, descriptor] of Object.entries(properties)) {
-									if (descriptor && descriptor.value) {
-										if (typeof descriptor.value === 'string') {
-											monitor.checkValue(descriptor.value, 'Object.create.' + prop);
-										}
-									}
-								}
-							}
-							return obj;
-						};
-
-						const originalAssign = Object.assign;
-						Object.assign = function(target, ...sources) {
-							const result = originalAssign.call(this, target, ...sources);
-							for (const source of sources) {
-								for (const [prop, value] of Object.entries(source)) {
-									if (typeof value === 'string') {
-										monitor.checkValue(value, 'Object.assign.' + prop);
-									}
-								}
-							}
-							return result;
-						};
-
-						const originalSet = Reflect.set;
-						Reflect.set = function(target, prop, value) {
-							if (typeof value === 'string') {
-								monitor.checkValue(value, target.constructor ? target.constructor.name + '.' + prop : 'Object.' + prop);
-							}
-							return originalSet.call(this, target, prop, value);
-						};
-
-						this.scanInterval = setInterval(() => {
-							this.scanObject(window, 'window');
-						}, 1000);
-
-						const windowHandler = {
-							get: (target, prop) => {
-								const value = target[prop];
-								if (typeof value === 'string') {
-									monitor.checkValue(value, 'window.' + String(prop));
-								}
-								return value;
-							},
-							set: (target, prop, value) => {
-								if (typeof value === 'string') {
-									monitor.checkValue(value, 'window.' + String(prop));
-								}
-								return Reflect.set(target, prop, value);
-							}
-						};
-
-						const windowProxy = new Proxy(window, windowHandler);
-						try {
-							Object.defineProperty(window, '__proto__', {
-								get: () => windowProxy.__proto__,
-								set: (value) => {
-									windowProxy.__proto__ = value;
-									return true;
-								},
-								configurable: true
-							});
-						} catch (e) {
-							console.warn('[ObjectMonitor] Could not proxy window.__proto__, continuing with limited monitoring');
-						}
-
-						const globalHandler = {
-							set: (target, prop, value) => {
-								if (typeof value === 'string') {
-									monitor.checkValue(value, 'global.' + String(prop));
-								}
-								return Reflect.set(target, prop, value);
-							}
-						};
-
-						const globalObject = Function('return this')();
-						const globalProxy = new Proxy(globalObject, globalHandler);
-						try {
-							Object.defineProperty(globalObject, '__proto__', {
-								get: () => globalProxy.__proto__,
-								set: (value) => {
-									globalProxy.__proto__ = value;
-									return true;
-								},
-								configurable: true
-							});
-						} catch (e) {
-							console.warn('[ObjectMonitor] Could not proxy global.__proto__, continuing with limited monitoring');
-						}
-						
-						console.log('%c[ObjectMonitor] Started monitoring all objects', 'color: #00ff00');
-					} catch (e) {
-						console.error('[ObjectMonitor] Failed to start:', e);
-					}
-				}
-			}
+// AddCustomString searches for value as a literal substring. Once any
+// custom search is added the patterns are no longer checked.
+func (m *ObjectMonitor) AddCustomString(value string) {
+	// A quoted literal compiles in Go and JavaScript alike
+	m.addCustomSearch("Custom String: "+value, regexp.QuoteMeta(value), "Custom String Match")
+}
 
-			scanObject(obj, path = 'window', depth = 0, visited = new Set()) {
-				if (depth > this.maxDepth) return;
-				if (!obj || typeof obj !== 'object') return;
-				if (visited.has(obj)) return;
-				if (this.ignoredPaths.has(path)) return;
-
-				visited.add(obj);
-				this.stats.objectsScanned++;
-
-				try {
-					for (const prop in obj) {
-						try {
-							const value = obj[prop];
-							const newPath = path + '.' + prop;
-							
-							if (typeof value === 'string') {
-								this.checkValue(value, newPath);
-							} else if (value && typeof value === 'object') {
-								this.scanObject(value, newPath, depth + 1, visited);
-							}
-						} catch (e) {}
-					}
-				} catch (e) {}
-			}
+// AddCustomStringIgnoreCase searches for value as a literal substring in
+// any letter case, so "secret" also finds "SECRET"
+func (m *ObjectMonitor) AddCustomStringIgnoreCase(value string) {
+	m.addCustomSearch("Custom String: "+value, foldLiteral(value), "Custom String Match")
+}
 
-			getStats() {
-				return this.stats;
-			}
+// foldLiteral quotes value like regexp.QuoteMeta, with each letter replaced
+// by a class of its cases. Unlike a flag, the classes read the same in Go
+// and JavaScript. Letters outside the Basic Multilingual Plane are left
+// alone, as JavaScript classes without the u flag split them in two.
+func foldLiteral(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		cases := []rune{r}
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			cases = append(cases, f)
 		}
-
-		const monitor = new ObjectMonitor({
-			debug: false,
-			maxDepth: 5
-		});
-
-		// Add patterns to monitor
-		monitor.addPattern(
-			'AWS Access Key',
-			/\\b(AKIA|ASIA)[A-Z0-9]{16}\\b/,
-			'AWS Access Key ID'
-		).addPattern(
-			'AWS Secret Key',
-			/\\b[A-Za-z0-9/+=]{40}(?![A-Za-z0-9/+=])/,
-			'AWS Secret Access Key'
-		).addPattern(
-			'Private Key',
-			/-----BEGIN (?:RSA|OPENSSH|DSA|EC|PGP) PRIVATE KEY-----/,
-			'Private Key Header'
-		).addPattern(
-			'API Key',
-			/(?:api[_-]?key|api[_-]?secret|client[_-]?secret)['\\"]?\\s*[:=]\\s*['"]([a-zA-Z0-9_\\-]{32,})['"]/i,
-			'API Key Assignment'
-		).addPattern(
-			'JWT Token',
-			/eyJ[A-Za-z0-9-_=]+\\.[A-Za-z0-9-_=]+\\.?[A-Za-z0-9-_.+/=]*$/,
-			'JWT Token'
-		);
-
-		// Start monitoring
-		monitor.start();
-	`
+		if len(cases) == 1 || slices.ContainsFunc(cases, func(c rune) bool { return c > 0xFFFF }) {
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			continue
+		}
+		b.WriteString("[" + string(cases) + "]")
+	}
+	return b.String()
 }
 
-func wrapText(text string, width int) []string {
-	if len(text) <= width {
-		return []string{text}
+// AddCustomRegex searches for values matching pattern. Once any custom
+// search is added the patterns are no longer checked.
+func (m *ObjectMonitor) AddCustomRegex(pattern string) error {
+	if _, err := compilePattern(pattern); err != nil {
+		return fmt.Errorf("custom regex %q: %w", pattern, err)
 	}
+	m.addCustomSearch("Custom Regex: "+pattern, pattern, "Custom Regex Match")
+	return nil
+}
 
-	var lines []string
-	// Split by newlines first
-	paragraphs := strings.Split(text, "\n")
-
-	for _, paragraph := range paragraphs {
-		// Then wrap each paragraph
-		for len(paragraph) > 0 {
-			if len(paragraph) <= width {
-				lines = append(lines, paragraph)
-				break
-			}
-			// Try to break at a space if possible
-			breakPoint := width
-			if breakPoint < len(paragraph) {
-				// Look for the last space before the break point
-				for i := breakPoint; i > 0; i-- {
-					if paragraph[i-1] == ' ' {
-						breakPoint = i
-						break
-					}
-				}
-			}
-			lines = append(lines, paragraph[:breakPoint])
-			paragraph = strings.TrimSpace(paragraph[breakPoint:])
+func (m *ObjectMonitor) addCustomSearch(name, pattern, description string) {
+	for _, c := range m.custom {
+		if c.name == name {
+			return
 		}
 	}
-	return lines
+	m.custom = append(m.custom, customSearch{name: name, monitoredPattern: mustPattern(pattern, description, DefaultSeverity)})
 }
 
-func printTableRow(w *os.File, pattern, path, value, description string) {
-	// Define column widths
-	const (
-		patternWidth = 15
-		pathWidth    = 30
-		valueWidth   = 40
-		descWidth    = 30
-	)
-
-	// Wrap each field
-	patternLines := wrapText(pattern, patternWidth)
-	pathLines := wrapText(path, pathWidth)
-	valueLines := wrapText(value, valueWidth)
-	descLines := wrapText(description, descWidth)
+// ClearPatterns removes every pattern, the default ones included, so only
+// the patterns added afterwards are monitored
+func (m *ObjectMonitor) ClearPatterns() {
+	m.patterns = make(map[string]monitoredPattern)
+}
 
-	// Find the maximum number of lines needed
-	maxLines := len(patternLines)
-	if len(pathLines) > maxLines {
-		maxLines = len(pathLines)
-	}
-	if len(valueLines) > maxLines {
-		maxLines = len(valueLines)
-	}
-	if len(descLines) > maxLines {
-		maxLines = len(descLines)
+// SelectPatterns restricts the monitored patterns to the include list, if
+// any, and then drops the exclude list. Names that do not match a pattern
+// are returned so callers can warn about them.
+func (m *ObjectMonitor) SelectPatterns(include, exclude []string) (unknown []string) {
+	for _, name := range append(append([]string{}, include...), exclude...) {
+		if _, ok := m.patterns[name]; !ok {
+			unknown = append(unknown, name)
+		}
 	}
 
-	// Print each line
-	for i := 0; i < maxLines; i++ {
-		pattern := ""
-		if i < len(patternLines) {
-			pattern = patternLines[i]
-		}
-		path := ""
-		if i < len(pathLines) {
-			path = pathLines[i]
+	if len(include) > 0 {
+		selected := make(map[string]bool)
+		for _, name := range include {
+			selected[name] = true
 		}
-		value := ""
-		if i < len(valueLines) {
-			value = valueLines[i]
-		}
-		desc := ""
-		if i < len(descLines) {
-			desc = descLines[i]
+		for name := range m.patterns {
+			if !selected[name] {
+				delete(m.patterns, name)
+			}
 		}
-
-		// Print the row with proper padding and red pattern
-		fmt.Fprintf(w, "│ \033[31m%-*s\033[0m │ %-*s │ %-*s │ %-*s │\n",
-			patternWidth, pattern,
-			pathWidth, path,
-			valueWidth, value,
-			descWidth, desc)
 	}
-
-	// Print bottom border for the last row
-	if maxLines > 0 {
-		fmt.Println("└" + strings.Repeat("─", patternWidth+2) + "┴" +
-			strings.Repeat("─", pathWidth+2) + "┴" +
-			strings.Repeat("─", valueWidth+2) + "┴" +
-			strings.Repeat("─", descWidth+2) + "┘")
+	for _, name := range exclude {
+		delete(m.patterns, name)
 	}
+	return unknown
 }
 
-func printUsage() {
-	fmt.Println(`
-OBJECTOR - JavaScript Object Monitor
-
-  A powerful tool for monitoring JavaScript objects and detecting exposed
-  credentials, API keys, and sensitive data in web applications.
-
-  USAGE:
-    objector -u <URL> [OPTIONS]
-
-  REQUIRED ARGUMENTS:
-    -u, --url <URL>              Target URL to monitor
-
-  OPTIONAL ARGUMENTS:
-    --timeout <duration>         Monitoring timeout (default: 20s)
-    --headers <headers>          Custom headers for requests
-    --string <custom_string>     Custom string to search for
-    --help, -h                   Show this help message
-
-  EXAMPLES:
-    objector -u [url]
-    objector -u [url] --timeout 30s
-    objector -u [url] --headers "Authorization: Bearer token"
-    objector -u [url] --string "my-secret-key"
-
-  DETECTED PATTERNS:
-    • AWS Access Keys (AKIA/ASIA format)
-    • AWS Secret Keys (40-character base64)
-    • Private Keys (RSA, DSA, EC, OpenSSH)
-    • JWT Tokens (eyJ format)
-    • Generic API Keys (32+ characters)
-`)
-}
-
-func main() {
-	// Parse command line flags
-	url := flag.String("u", "", "URL to monitor (required)")
-	urlLong := flag.String("url", "", "URL to monitor (required)")
-	timeout := flag.Duration("timeout", 20*time.Second, "Monitoring timeout")
-	headers := flag.String("headers", "", "Headers to include in requests (format: 'HEADER: VALUE,HEADER2: VALUE2')")
-	customString := flag.String("string", "", "Custom string to search for (if provided, ignores default patterns)")
-	help := flag.Bool("help", false, "Show help message")
-	helpShort := flag.Bool("h", false, "Show help message")
-
-	// Custom usage function
-	flag.Usage = printUsage
-
-	flag.Parse()
-
-	// Check if help is requested
-	if *help || *helpShort {
-		printUsage()
-		os.Exit(0)
-	}
-
-	// Check if no arguments provided
-	if len(os.Args) == 1 {
-		printUsage()
-		os.Exit(1)
+// Patterns returns the monitored patterns sorted by name
+func (m *ObjectMonitor) Patterns() []Pattern {
+	patterns := make([]Pattern, 0, len(m.patterns))
+	for name, p := range m.patterns {
+		patterns = append(patterns, Pattern{
+			Name:        name,
+			Pattern:     p.pattern,
+			Description: p.description,
+			Severity:    p.severity,
+		})
 	}
+	sort.Slice(patterns, func(i, j int) bool {
+		return patterns[i].Name < patterns[j].Name
+	})
+	return patterns
+}
 
-	// Use either -u or --url
-	targetURL := *url
-	if targetURL == "" {
-		targetURL = *urlLong
+// IgnoredPaths returns the ignored path rules sorted alphabetically
+func (m *ObjectMonitor) IgnoredPaths() []string {
+	paths := make([]string, 0, len(m.ignoredPaths))
+	for path := range m.ignoredPaths {
+		paths = append(paths, path)
 	}
+	sort.Strings(paths)
+	return paths
+}
 
-	if targetURL == "" {
-		fmt.Println("\033[31mError: URL is required. Use -u or --url to specify the target URL.\033[0m")
-		fmt.Println("Run 'objector --help' for usage information.")
-		os.Exit(1)
+// matchesPattern re-checks a value reported by the injected scripts against
+// the Go compilation of the named pattern or custom search. Names the
+// monitor does not know are accepted.
+func (m *ObjectMonitor) matchesPattern(name, value string) bool {
+	for _, c := range m.custom {
+		if c.name == name {
+			return c.re.MatchString(value)
+		}
 	}
+	p, ok := m.patterns[name]
+	return !ok || p.re.MatchString(value)
+}
 
-	// Animation frames for the spinner
-	spinnerFrames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-	spinnerIndex := 0
-
-	// Function to print the spinner
-	printSpinner := func() {
-		fmt.Printf("\r\033[K%s Scanning for secrets...", spinnerFrames[spinnerIndex])
-		spinnerIndex = (spinnerIndex + 1) % len(spinnerFrames)
+// SearchNames returns the names matches can be reported under: the custom
+// searches in the order they were added if any, otherwise the patterns
+// sorted by name
+func (m *ObjectMonitor) SearchNames() []string {
+	var names []string
+	if len(m.custom) > 0 {
+		for _, c := range m.custom {
+			names = append(names, c.name)
+		}
+		return names
 	}
-
-	// Clear the spinner line
-	clearSpinner := func() {
-		fmt.Print("\r\033[K")
+	for name := range m.patterns {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	// Parse headers
-	headerMap := make(map[string]string)
-	if *headers != "" {
-		headerPairs := strings.Split(*headers, ",")
-		for _, pair := range headerPairs {
-			parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
-			if len(parts) == 2 {
-				headerMap[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-			}
+// severityOf returns the severity of the named pattern or custom search
+func (m *ObjectMonitor) severityOf(name string) Severity {
+	for _, c := range m.custom {
+		if c.name == name {
+			return c.severity
 		}
 	}
+	if p, ok := m.patterns[name]; ok {
+		return p.severity
+	}
+	return DefaultSeverity
+}
 
-	// Create a new context with options to suppress errors
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", true),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.Flag("log-level", "3"), // Suppress all logging
-		chromedp.Flag("silent", true),
-	)
-
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancel()
-
-	// Create a new context
-	ctx, cancel := chromedp.NewContext(allocCtx)
-	defer cancel()
-
-	// Set timeout
-	ctx, cancel = context.WithTimeout(ctx, *timeout)
-	defer cancel()
-
-	// Create monitor
-	monitor := NewObjectMonitor()
-
-	// Track printed secrets
-	seenSecrets := make(map[string]bool)
-
-	// Define column widths
-	const (
-		patternWidth = 15
-		pathWidth    = 30
-		valueWidth   = 40
-		descWidth    = 30
-	)
-
-	// Print top border
-	fmt.Println("┌" + strings.Repeat("─", patternWidth+2) + "┬" +
-		strings.Repeat("─", pathWidth+2) + "┬" +
-		strings.Repeat("─", valueWidth+2) + "┬" +
-		strings.Repeat("─", descWidth+2) + "┐")
-
-	// Print header
-	fmt.Printf("│ \033[1m%-*s\033[0m │ %-*s │ %-*s │ %-*s │\n",
-		patternWidth, "Pattern",
-		pathWidth, "Path",
-		valueWidth, "Value",
-		descWidth, "Description")
-
-	// Print header separator
-	fmt.Println("├" + strings.Repeat("─", patternWidth+2) + "┼" +
-		strings.Repeat("─", pathWidth+2) + "┼" +
-		strings.Repeat("─", valueWidth+2) + "┼" +
-		strings.Repeat("─", descWidth+2) + "┤")
-
-	// Run the browser
-	err := chromedp.Run(ctx,
-		// Set headers for all requests
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			headers := make(map[string]interface{})
-			for k, v := range headerMap {
-				headers[k] = v
-			}
-			return network.SetExtraHTTPHeaders(network.Headers(headers)).Do(ctx)
-		}),
-
-		// Navigate to the target page
-		chromedp.Navigate(targetURL),
-
-		// Wait for the page to be fully loaded
-		chromedp.WaitReady("body", chromedp.ByQuery),
-
-		// Inject our monitoring script
-		chromedp.Evaluate(monitor.GetMonitoringScript(), nil),
-
-		// Set custom search string if provided
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			if *customString != "" {
-				return chromedp.Evaluate(fmt.Sprintf(`window.__customSearchString = "%s";`, *customString), nil).Do(ctx)
-			}
-			return nil
-		}),
-
-		// Check for credentials multiple times
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			// Now do our credential scan
-			var result string
-			var finalStats struct {
-				ObjectsScanned int `json:"objectsScanned"`
-				MatchesFound   int `json:"matchesFound"`
-			}
-
-			err := chromedp.Evaluate(`
-				(function() {
-					try {
-						let matches = [];
-						let visited = new Set();
-						let stats = {
-							objectsScanned: 0,
-							matchesFound: 0
-						};
-						
-						function checkValue(value, path) {
-							if (typeof value !== 'string') return;
-							
-							// Check for custom string if provided
-							if (window.__customSearchString && value.includes(window.__customSearchString)) {
-								stats.matchesFound++;
-								matches.push({
-									pattern: 'Custom String',
-									path: path,
-									value: value,
-									description: 'Custom String Match'
-								});
-								return;
-							}
-							
-							// Only check default patterns if no custom string is provided
-							if (!window.__customSearchString) {
-								// Check for AWS Access Key
-								if (value.match(/AKIA[A-Z0-9]{16}/)) {
-									stats.matchesFound++;
-									matches.push({
-										pattern: 'AWS Access Key',
-										path: path,
-										value: value,
-										description: 'AWS Access Key ID'
-									});
-									return;
-								}
-								
-								// Check for AWS Secret Key
-								if (value.match(/secret[a-zA-Z0-9]{40}/)) {
-									stats.matchesFound++;
-									matches.push({
-										pattern: 'AWS Secret Key',
-										path: path,
-										value: value,
-										description: 'AWS Secret Access Key'
-									});
-									return;
-								}
-								
-								// Check for Private Key
-								if (value.match(/-----BEGIN (?:RSA|OPENSSH|DSA|EC|PGP) PRIVATE KEY-----/)) {
-									stats.matchesFound++;
-									matches.push({
-										pattern: 'Private Key',
-										path: path,
-										value: value,
-										description: 'Private Key Header'
-									});
-									return;
-								}
-								
-								// Check for JWT Token
-								if (value.match(/eyJ[A-Za-z0-9-_=]+\.[A-Za-z0-9-_=]+\.?[A-Za-z0-9-_.+/=]*$/)) {
-									stats.matchesFound++;
-									matches.push({
-										pattern: 'JWT Token',
-										path: path,
-										value: value,
-										description: 'JWT Token'
-									});
-									return;
-								}
-							}
-						}
-						
-						function scanObject(obj, path = '', depth = 0) {
-							if (depth > 5) return;
-							if (!obj || typeof obj !== 'object') return;
-							if (visited.has(obj)) return;
-							
-							const ignoredPaths = ['performance', 'localStorage', 'sessionStorage', 'indexedDB', 'webkitStorageInfo', 'chrome', 'document', 'history'];
-							if (ignoredPaths.includes(path.split('.').pop())) return;
-							
-							visited.add(obj);
-							stats.objectsScanned++;
-							
-							try {
-								for (const prop in obj) {
-									try {
-										const value = obj[prop];
-										const newPath = path ? path + '.' + prop : prop;
-										
-										if (typeof value === 'string') {
-											checkValue(value, newPath);
-										} else if (value && typeof value === 'object') {
-											scanObject(value, newPath, depth + 1);
-										}
-									} catch (e) {
-										// Ignore property access errors
-									}
-								}
-							} catch (e) {
-								// Ignore object access errors
-							}
-						}
-						
-						// Get the global object
-						const globalObject = Function('return this')();
-						
-						// Start scanning from global object
-						scanObject(globalObject);
-						
-						return JSON.stringify({
-							matches: matches,
-							stats: stats
-						});
-					} catch (e) {
-						return JSON.stringify({ error: e.message });
-					}
-				})()
-			`, &result).Do(ctx)
-
-			if err != nil {
-				return nil
-			}
-
-			// Parse and format the matches
-			var response struct {
-				Matches []struct {
-					Pattern     string `json:"pattern"`
-					Path        string `json:"path"`
-					Value       string `json:"value"`
-					Description string `json:"description"`
-				} `json:"matches"`
-				Stats struct {
-					ObjectsScanned int `json:"objectsScanned"`
-					MatchesFound   int `json:"matchesFound"`
-				} `json:"stats"`
-			}
-
-			if err := json.Unmarshal([]byte(result), &response); err != nil {
-				return nil
-			}
-
-			// Print only new matches
-			for _, match := range response.Matches {
-				// Create a unique key for this secret
-				secretKey := match.Path + ":" + match.Value
-				if !seenSecrets[secretKey] {
-					seenSecrets[secretKey] = true
-					printTableRow(os.Stdout, match.Pattern, match.Path, match.Value, match.Description)
-				}
-			}
+// inValueLengthRange reports whether value satisfies the configured length
+// limits, where a limit of 0 means unbounded
+func (m *ObjectMonitor) inValueLengthRange(value string) bool {
+	n := utf16Len(value)
+	return (m.minValueLen <= 0 || n >= m.minValueLen) && (m.maxValueLen <= 0 || n <= m.maxValueLen)
+}
 
-			// Add a continuous monitoring loop
-			ticker := time.NewTicker(1 * time.Second)
-			defer ticker.Stop()
-
-			// Print initial spinner
-			printSpinner()
-
-			for {
-				select {
-				case <-ticker.C:
-					// Update spinner
-					printSpinner()
-
-					// Re-run the scan
-					err = chromedp.Evaluate(`
-						(function() {
-							try {
-								let matches = [];
-								let visited = new Set();
-								let stats = {
-									objectsScanned: 0,
-									matchesFound: 0
-								};
-								
-								function checkValue(value, path) {
-									if (typeof value !== 'string') return;
-									
-									if (value.match(/AKIA[A-Z0-9]{16}/)) {
-										stats.matchesFound++;
-										matches.push({
-											pattern: 'AWS Access Key',
-											path: path,
-											value: value,
-											description: 'AWS Access Key ID'
-										});
-										return;
-									}
-									
-									if (value.match(/secret[a-zA-Z0-9]{40}/)) {
-										stats.matchesFound++;
-										matches.push({
-											pattern: 'AWS Secret Key',
-											path: path,
-											value: value,
-											description: 'AWS Secret Access Key'
-										});
-										return;
-									}
-									
-									if (value.match(/-----BEGIN (?:RSA|OPENSSH|DSA|EC|PGP) PRIVATE KEY-----/)) {
-										stats.matchesFound++;
-										matches.push({
-											pattern: 'Private Key',
-											path: path,
-											value: value,
-											description: 'Private Key Header'
-										});
-										return;
-									}
-									
-									if (value.match(/eyJ[A-Za-z0-9-_=]+\.[A-Za-z0-9-_=]+\.?[A-Za-z0-9-_.+/=]*$/)) {
-										stats.matchesFound++;
-										matches.push({
-											pattern: 'JWT Token',
-											path: path,
-											value: value,
-											description: 'JWT Token'
-										});
-										return;
-									}
-								}
-								
-								function scanObject(obj, path = '', depth = 0) {
-									if (depth > 5) return;
-									if (!obj || typeof obj !== 'object') return;
-									if (visited.has(obj)) return;
-									
-									const ignoredPaths = ['performance', 'localStorage', 'sessionStorage', 'indexedDB', 'webkitStorageInfo', 'chrome', 'document', 'history'];
-									if (ignoredPaths.includes(path.split('.').pop())) return;
-									
-									visited.add(obj);
-									stats.objectsScanned++;
-									
-									try {
-										for (const prop in obj) {
-											try {
-												const value = obj[prop];
-												const newPath = path ? path + '.' + prop : prop;
-												
-												if (typeof value === 'string') {
-													checkValue(value, newPath);
-												} else if (value && typeof value === 'object') {
-													scanObject(value, newPath, depth + 1);
-												}
-											} catch (e) {
-												// Ignore property access errors
-											}
-										}
-									} catch (e) {
-										// Ignore object access errors
-									}
-								}
-								
-								// Get the global object
-								const globalObject = Function('return this')();
-								
-								// Start scanning from global object
-								scanObject(globalObject);
-								
-								return JSON.stringify({
-									matches: matches,
-									stats: stats
-								});
-							} catch (e) {
-								return JSON.stringify({ error: e.message });
-							}
-						})()
-					`, &result).Do(ctx)
-
-					if err != nil {
-						continue
-					}
-
-					if err := json.Unmarshal([]byte(result), &response); err != nil {
-						continue
-					}
-
-					// Print only new matches
-					for _, match := range response.Matches {
-						// Create a unique key for this secret
-						secretKey := match.Path + ":" + match.Value
-						if !seenSecrets[secretKey] {
-							seenSecrets[secretKey] = true
-							printTableRow(os.Stdout, match.Pattern, match.Path, match.Value, match.Description)
-						}
-					}
-
-					// Update final stats
-					finalStats = response.Stats
-
-				case <-ctx.Done():
-					// Clear the spinner before showing stats
-					clearSpinner()
-
-					// Print final stats before exiting
-					fmt.Println("\n┌" + strings.Repeat("─", 50) + "┐")
-					fmt.Println("│ \033[1mMonitoring Statistics\033[0m" + strings.Repeat(" ", 28) + "│")
-					fmt.Println("├" + strings.Repeat("─", 50) + "┤")
-					fmt.Printf("│ Total Objects Scanned: %-25d │\n", finalStats.ObjectsScanned)
-					fmt.Printf("│ Total Matches Found:   %-25d │\n", finalStats.MatchesFound)
-					fmt.Println("└" + strings.Repeat("─", 50) + "┘")
-					return nil
-				}
-			}
-		}),
+// utf16Len returns the length of s as JavaScript counts it
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
 
-		// Wait for the timeout
-		chromedp.Sleep(*timeout),
-	)
+// SetColor enables or disables the ANSI colors used by LogMatch
+func (m *ObjectMonitor) SetColor(enabled bool) {
+	m.color = enabled
+}
 
-	if err != nil {
-		log.Fatal(err)
+// LogMatch handles a detected match
+func (m *ObjectMonitor) LogMatch(match Match) {
+	// Print match in a clean format
+	if m.color {
+		fmt.Printf("\033[31m[ObjectMonitor Match]\033[0m\n")
+	} else {
+		fmt.Printf("[ObjectMonitor Match]\n")
 	}
+	fmt.Printf("Timestamp:   %s\n", match.Timestamp.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Pattern:     %s\n", match.Pattern)
+	fmt.Printf("Severity:    %s\n", match.Severity)
+	fmt.Printf("Path:        %s\n", match.Path)
+	fmt.Printf("Value:       %s\n", match.Value)
+	if match.Captured != "" {
+		fmt.Printf("Captured:    %s\n", match.Captured)
+	}
+	if match.Context != nil {
+		fmt.Printf("Context:     %s[match]%s\n", match.Context.Before, match.Context.After)
+		if len(match.Context.Siblings) > 0 {
+			fmt.Printf("Siblings:    %s\n", strings.Join(match.Context.Siblings, ", "))
+		}
+	}
+	fmt.Printf("Description: %s\n\n", match.Description)
 }
@@ -0,0 +1,165 @@
+package objector
+
+import (
+	"slices"
+	"strings"
+)
+
+// matchesPathRule reports whether path lies at or below the object path
+// described by rule. Rules are dot separated full paths such as
+// window.localStorage, where a * segment matches any single property name,
+// so window.chrome.* skips everything below window.chrome.
+func matchesPathRule(rule, path string) bool {
+	ruleSegments := strings.Split(rule, ".")
+	pathSegments := strings.Split(path, ".")
+	if len(ruleSegments) > len(pathSegments) {
+		return false
+	}
+	for i, segment := range ruleSegments {
+		if segment != "*" && segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSegments reports whether the segments of a path match those of a
+// glob as a whole. Globs are dot separated like paths, where * in a segment
+// matches any characters of one property name and a ** segment matches any
+// number of names, so **.__reactFiber* matches every property whose name
+// starts with __reactFiber.
+func matchSegments(glob, path []string) bool {
+	if len(glob) == 0 {
+		return len(path) == 0
+	}
+	if glob[0] == "**" {
+		return matchSegments(glob[1:], path) || (len(path) > 0 && matchSegments(glob, path[1:]))
+	}
+	return len(path) > 0 && matchSegment(glob[0], path[0]) && matchSegments(glob[1:], path[1:])
+}
+
+// matchSegment matches one property name against a glob segment
+func matchSegment(glob, name string) bool {
+	parts := strings.Split(glob, "*")
+	if len(parts) == 1 {
+		return glob == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, parts[len(parts)-1])
+}
+
+// globalRoots are the names the monitor gives the global object at the start
+// of a path
+var globalRoots = []string{"window", "global", "globalThis"}
+
+// globalAliases are the properties of the global object that refer back to
+// it, so window.self.foo is window.foo. The scripts also treat top and
+// parent as aliases wherever they are the global object, in the top frame.
+var globalAliases = []string{"window", "self", "globalThis", "frames"}
+
+// canonicalPath collapses a chain of aliases of the global object at the
+// start of path, as in window.window.self.foo, to the single window root
+// used for every path, so one property is reported under one path
+func canonicalPath(path string) string {
+	segments := strings.Split(path, ".")
+	if !slices.Contains(globalRoots, segments[0]) {
+		return path
+	}
+	i := 1
+	for i < len(segments)-1 && slices.Contains(globalAliases, segments[i]) {
+		i++
+	}
+	return strings.Join(append([]string{"window"}, segments[i:]...), ".")
+}
+
+// isIgnoredPath reports whether path is covered by any of the ignored path
+// rules, or lies at or below a path matching an exclude glob
+func (m *ObjectMonitor) isIgnoredPath(path string) bool {
+	for rule := range m.ignoredPaths {
+		if matchesPathRule(rule, path) {
+			return true
+		}
+	}
+	segments := strings.Split(path, ".")
+	for _, glob := range m.excludePaths {
+		globSegments := strings.Split(glob, ".")
+		for i := 1; i <= len(segments); i++ {
+			if matchSegments(globSegments, segments[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathRulesScript mirrors matchesPathRule, the glob matching and
+// canonicalPath for the injected scripts
+const pathRulesScript = `
+		// Properties that are the global object here, found by identity
+		// rather than by name
+		const globalAliases = (() => {
+			const root = Function('return this')();
+			return ['window', 'self', 'globalThis', 'frames', 'top', 'parent'].filter(name => {
+				try {
+					return root[name] === root;
+				} catch (e) {
+					return false;
+				}
+			});
+		})();
+
+		function canonicalPath(path) {
+			const segments = path.split('.');
+			if (!['window', 'global', 'globalThis'].includes(segments[0])) return path;
+			let i = 1;
+			while (i < segments.length - 1 && globalAliases.includes(segments[i])) i++;
+			return ['window'].concat(segments.slice(i)).join('.');
+		}
+
+		function matchesPathRule(rule, path) {
+			const ruleSegments = rule.split('.');
+			const pathSegments = path.split('.');
+			if (ruleSegments.length > pathSegments.length) return false;
+			return ruleSegments.every((segment, i) => segment === '*' || segment === pathSegments[i]);
+		}
+
+		function isIgnoredPath(rules, path) {
+			return rules.some(rule => matchesPathRule(rule, path));
+		}
+
+		function matchSegments(glob, path) {
+			if (glob.length === 0) return path.length === 0;
+			if (glob[0] === '**') {
+				return matchSegments(glob.slice(1), path) || (path.length > 0 && matchSegments(glob, path.slice(1)));
+			}
+			return path.length > 0 && matchSegment(glob[0], path[0]) && matchSegments(glob.slice(1), path.slice(1));
+		}
+
+		function matchSegment(glob, name) {
+			const parts = glob.split('*');
+			if (parts.length === 1) return glob === name;
+			if (!name.startsWith(parts[0])) return false;
+			name = name.slice(parts[0].length);
+			for (const part of parts.slice(1, -1)) {
+				const i = name.indexOf(part);
+				if (i < 0) return false;
+				name = name.slice(i + part.length);
+			}
+			return name.endsWith(parts[parts.length - 1]);
+		}
+
+		function isExcludedPath(globs, path) {
+			const segments = path.split('.');
+			return globs.some(glob => matchSegments(glob.split('.'), segments));
+		}
+`
@@ -0,0 +1,108 @@
+package objector
+
+import (
+	"strings"
+	"testing"
+)
+
+// pathRuleTests are shared with the test of the injected scripts
+var pathRuleTests = []struct {
+	rule, path string
+	want       bool
+}{
+	{"window.localStorage", "window.localStorage", true},
+	{"window.localStorage", "window.localStorage.token", true},
+	{"window.localStorage", "window.localStorageX", false},
+	{"window.localStorage", "window", false},
+	{"window.chrome.*", "window.chrome.runtime", true},
+	{"window.chrome.*", "window.chrome.runtime.id", true},
+	{"window.chrome.*", "window.chrome", false},
+	{"window.*.secret", "window.config.secret", true},
+	{"window.*.secret", "window.config.public", false},
+	{"*", "window", true},
+	{"window.a", "window.b.a", false},
+}
+
+// segmentTests are shared with the test of the injected scripts
+var segmentTests = []struct {
+	glob, path string
+	want       bool
+}{
+	{"window.token", "window.token", true},
+	{"window.token", "window.token.value", false},
+	{"window.*", "window.token", true},
+	{"window.*", "window", false},
+	{"window.tok*", "window.token", true},
+	{"window.*en", "window.token", true},
+	{"window.t*k*n", "window.token", true},
+	{"window.t*x*n", "window.token", false},
+	{"window.ab*ba", "window.aba", false},
+	{"window.a*a", "window.a", false},
+	{"**.__reactFiber*", "window.app.div.__reactFiber$x1", true},
+	{"**.__reactFiber*", "__reactFiber$x1", true},
+	{"**.__reactFiber*", "window.app.__reactProps$x1", false},
+	{"**", "window.a.b", true},
+	{"**", "", true},
+	{"window.**.secret", "window.secret", true},
+	{"window.**.secret", "window.a.b.secret", true},
+	{"window.**.secret", "window.a.b.secret.c", false},
+	{"window.**", "window", true},
+}
+
+func TestMatchesPathRule(t *testing.T) {
+	for _, tt := range pathRuleTests {
+		if got := matchesPathRule(tt.rule, tt.path); got != tt.want {
+			t.Errorf("matchesPathRule(%q, %q) = %t, want %t", tt.rule, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatchSegments(t *testing.T) {
+	for _, tt := range segmentTests {
+		if got := matchSegments(strings.Split(tt.glob, "."), splitPath(tt.path)); got != tt.want {
+			t.Errorf("matchSegments(%q, %q) = %t, want %t", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"window.token", "window.token"},
+		{"window.window.self.token", "window.token"},
+		{"globalThis.frames.token", "window.token"},
+		{"global.token", "window.token"},
+		{"window.self", "window.self"},
+		{"window.config.self.token", "window.config.self.token"},
+		{"localStorage['token']", "localStorage['token']"},
+	}
+	for _, tt := range tests {
+		if got := canonicalPath(tt.path); got != tt.want {
+			t.Errorf("canonicalPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIsIgnoredPath(t *testing.T) {
+	m := NewObjectMonitor()
+	m.excludePaths = []string{"**.__reactFiber*"}
+	for path, want := range map[string]bool{
+		"window.localStorage.token":          true,
+		"window.app.__reactFiber$x1":         true,
+		"window.app.__reactFiber$x1.child.a": true,
+		"window.app.token":                   false,
+	} {
+		if got := m.isIgnoredPath(path); got != want {
+			t.Errorf("isIgnoredPath(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+// splitPath splits a path into its segments, none for the empty path
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
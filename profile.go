@@ -0,0 +1,86 @@
+package objector
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Profile breaks down where the time of a scan went, to tune the depth,
+// interval and budgets of the scan. It is only reported with
+// Options.Profile.
+type Profile struct {
+	// Launch is the time taken to start the browser
+	Launch time.Duration
+	// Navigation is the time from loading the page until its body is ready,
+	// retries and redirects included
+	Navigation time.Duration
+	// Setup is the time spent in the post-load script and actions
+	Setup time.Duration
+	// Injection is the time taken to install the monitoring script
+	Injection time.Duration
+	// Scanning is the time spent in scan passes over the object graph
+	Scanning time.Duration
+	// Passes lists every scan pass in the order they ran
+	Passes []Pass
+}
+
+// Pass is the profile of a single scan pass
+type Pass struct {
+	Duration time.Duration
+	// Objects is the number of objects visited, in every frame
+	Objects int
+}
+
+// ObjectsPerSecond is the scan rate over every pass
+func (p Profile) ObjectsPerSecond() float64 {
+	if p.Scanning <= 0 {
+		return 0
+	}
+	objects := 0
+	for _, pass := range p.Passes {
+		objects += pass.Objects
+	}
+	return float64(objects) / p.Scanning.Seconds()
+}
+
+// SlowestPass returns the duration of the longest pass
+func (p Profile) SlowestPass() time.Duration {
+	var slowest time.Duration
+	for _, pass := range p.Passes {
+		slowest = max(slowest, pass.Duration)
+	}
+	return slowest
+}
+
+// addPass records a pass that started at start
+func (p *Profile) addPass(start time.Time, response scanResponse) {
+	d := time.Since(start)
+	p.Scanning += d
+	p.Passes = append(p.Passes, Pass{Duration: d, Objects: response.Stats.ObjectsScanned})
+}
+
+// MarshalJSON encodes the durations in seconds
+func (p Profile) MarshalJSON() ([]byte, error) {
+	passes := p.Passes
+	if passes == nil {
+		passes = []Pass{}
+	}
+	return json.Marshal(struct {
+		Launch           float64 `json:"launch"`
+		Navigation       float64 `json:"navigation"`
+		Setup            float64 `json:"setup"`
+		Injection        float64 `json:"injection"`
+		Scanning         float64 `json:"scanning"`
+		ObjectsPerSecond float64 `json:"objectsPerSecond"`
+		Passes           []Pass  `json:"passes"`
+	}{p.Launch.Seconds(), p.Navigation.Seconds(), p.Setup.Seconds(), p.Injection.Seconds(),
+		p.Scanning.Seconds(), p.ObjectsPerSecond(), passes})
+}
+
+// MarshalJSON encodes the duration in seconds
+func (p Pass) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Duration float64 `json:"duration"`
+		Objects  int     `json:"objects"`
+	}{p.Duration.Seconds(), p.Objects})
+}
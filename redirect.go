@@ -0,0 +1,132 @@
+package objector
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// ErrRedirected is returned when the page answers with an HTTP redirect and
+// Options.NoFollowRedirects is set
+var ErrRedirected = errors.New("page redirected")
+
+// redirectTracker follows the HTTP redirects of the page's own navigation,
+// starting at the requested URL. With block set it stops the first one
+// before the redirected request is sent.
+type redirectTracker struct {
+	mu      sync.Mutex
+	chain   []string
+	block   bool
+	blocked string
+}
+
+func newRedirectTracker(start string, block bool) *redirectTracker {
+	return &redirectTracker{chain: []string{start}, block: block}
+}
+
+// enable intercepts document responses so redirects can be stopped. It is a
+// no-op unless redirects are blocked.
+func (r *redirectTracker) enable(ctx context.Context) error {
+	if !r.block {
+		return nil
+	}
+	return fetch.Enable().WithPatterns([]*fetch.RequestPattern{{
+		URLPattern:   "*",
+		ResourceType: network.ResourceTypeDocument,
+		RequestStage: fetch.RequestStageResponse,
+	}}).Do(ctx)
+}
+
+// handle is the target listener of the tracker. Paused requests are answered
+// on a goroutine, as listeners must not block.
+func (r *redirectTracker) handle(ctx context.Context, ev interface{}) {
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if ev.Type != network.ResourceTypeDocument || ev.RedirectResponse == nil {
+			return
+		}
+		r.mu.Lock()
+		if ev.RedirectResponse.URL == r.chain[len(r.chain)-1] {
+			r.chain = append(r.chain, ev.Request.URL)
+		}
+		r.mu.Unlock()
+
+	case *fetch.EventRequestPaused:
+		location := ""
+		if ev.ResponseStatusCode >= 300 && ev.ResponseStatusCode < 400 {
+			for _, h := range ev.ResponseHeaders {
+				if strings.EqualFold(h.Name, "Location") {
+					location = h.Value
+				}
+			}
+		}
+		r.mu.Lock()
+		stop := location != "" && r.blocked == "" && ev.Request.URL == r.chain[len(r.chain)-1]
+		if stop {
+			r.blocked = resolveURL(ev.Request.URL, location)
+		}
+		r.mu.Unlock()
+
+		c := chromedp.FromContext(ctx)
+		execCtx := cdp.WithExecutor(ctx, c.Target)
+		go func() {
+			if stop {
+				fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient).Do(execCtx)
+			} else {
+				fetch.ContinueRequest(ev.RequestID).Do(execCtx)
+			}
+		}()
+	}
+}
+
+// redirects returns the requested URL followed by every URL it redirected to
+func (r *redirectTracker) redirects() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.chain...)
+}
+
+// blockedRedirect returns the location of the redirect that was stopped,
+// if any
+func (r *redirectTracker) blockedRedirect() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.blocked
+}
+
+// resolveURL resolves a Location header against the URL it was sent for
+func resolveURL(base, location string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return location
+	}
+	l, err := b.Parse(location)
+	if err != nil {
+		return location
+	}
+	return l.String()
+}
+
+// sameURL reports whether two URLs are equal once the root path "/" that
+// browsers add is accounted for, e.g. "https://example.com" and
+// "https://example.com/"
+func sameURL(a, b string) bool {
+	pa, errA := url.Parse(a)
+	pb, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	for _, u := range []*url.URL{pa, pb} {
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	return pa.String() == pb.String()
+}
@@ -0,0 +1,1212 @@
+package objector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Deduplication modes
+const (
+	// DedupByPath reports a value again when it is seen at a new path
+	DedupByPath = "path"
+	// DedupByValue reports each value once, collecting every path it was
+	// seen at in Match.Paths
+	DedupByValue = "value"
+)
+
+// Options configures a scan
+type Options struct {
+	// Patterns are monitored in addition to the default patterns
+	Patterns []Pattern
+	// NoDefaultPatterns leaves the default patterns out, so only Patterns
+	// are monitored
+	NoDefaultPatterns bool
+	// IncludePatterns, if set, restricts the scan to the named patterns
+	IncludePatterns []string
+	// ExcludePatterns are pattern names that are not evaluated
+	ExcludePatterns []string
+	// MinSeverity, if set, drops matches of less severe patterns
+	MinSeverity Severity
+	// Headers are sent with every request made by the page, including
+	// client-side navigations, its frames, those rendered out of process
+	// too, and the popups it opens
+	Headers map[string]string
+	// Content, if set, is HTML loaded into a blank page in place of
+	// navigating to the URL, which then only names the page in matches.
+	// Its inline scripts run as they would in a loaded page.
+	Content string
+	// Timeout bounds how long the page is monitored
+	Timeout time.Duration
+	// Grace, if set, is the time given after Timeout to record the matches
+	// the monitor pushed last and to run one final pass, so findings made
+	// just before the deadline are not lost
+	Grace time.Duration
+	// MaxDepth limits how deep the object graph is walked (default: 5)
+	MaxDepth int
+	// IgnoredPaths are object paths whose subtrees are never scanned, such
+	// as window.localStorage. A * segment matches any property name.
+	IgnoredPaths []string
+	// ExcludePaths are globs of object paths whose subtrees are never
+	// scanned, matched against the full path, e.g. window.webpackChunk* or
+	// **.__reactFiber*. A * matches within a property name, a ** segment
+	// matches any number of them.
+	ExcludePaths []string
+	// CustomString, if set, replaces the patterns with a substring search
+	//
+	// Deprecated: use CustomStrings
+	CustomString string
+	// CustomStrings and CustomRegexes, if set, replace the patterns with
+	// substring and regular expression searches. Each is reported under its
+	// own pattern name, such as "Custom String: value".
+	CustomStrings []string
+	CustomRegexes []string
+	// IgnoreCase makes the custom string searches match in any letter case
+	IgnoreCase bool
+	// ScanStorage also scans the localStorage and sessionStorage entries
+	ScanStorage bool
+	// ScanDOM also scans the attribute values of every element, such as
+	// data-* attributes and inline event handlers. Matches have paths like
+	// dom:div[3]@data-api-key, the fourth div of the document.
+	ScanDOM bool
+	// ScanSourceMaps also loads the source maps the page's scripts reference
+	// and scans the original sources they embed. Matches have paths like
+	// sourcemap:webpack://app/src/config.js and the matched text as value.
+	ScanSourceMaps bool
+	// ScanFrames also scans every iframe whose JavaScript is reachable from
+	// the page. Matches in a frame have paths prefixed with the frame URL.
+	// Cross-origin frames usually run out of process and are skipped.
+	ScanFrames bool
+	// Domains, if set, restricts frame scanning to frames served from these
+	// hosts or their subdomains, e.g. "example.com"
+	Domains []string
+	// DeepScan also walks non-enumerable and inherited properties, invoking
+	// getters. Getters can have side effects in the page.
+	DeepScan bool
+	// DecodeBase64 also checks the text that long base64 strings decode to,
+	// up to three layers deep. Such matches have the path of the string
+	// wrapped as "base64-decoded(<path>)" and the decoded value.
+	DecodeBase64 bool
+	// ScanBudget caps the objects visited by a single pass over the object
+	// graph (default: DefaultScanBudget, negative for no limit)
+	ScanBudget int
+	// ScanTimeBudget caps the duration of a single pass (default:
+	// DefaultScanTimeBudget, negative for no limit). A pass that exceeds
+	// either budget reports the matches found so far. Both also bound each
+	// walk of the in-page monitor.
+	ScanTimeBudget time.Duration
+	// JSMatchCap caps the matches the injected scripts hold in the page
+	// (default: DefaultJSMatchCap, negative for no limit), so a page leaking
+	// endless secrets cannot exhaust the memory of its tab. A pass stops
+	// once it has collected that many matches, and the monitor stops
+	// pushing new ones once it remembers that many, which leaves them to
+	// the passes. Either sets Stats.CappedScans.
+	JSMatchCap int
+	// Once scans the page a single time after it loads and returns without
+	// monitoring it until Timeout
+	Once bool
+	// SinceStart only reports what appears while the page is monitored: the
+	// matches of the first pass are taken as already there and counted in
+	// Stats.ExistingMatches instead, so later passes and the monitor only
+	// report new values, and new paths of them. It cannot be combined with
+	// Once.
+	SinceStart bool
+	// Interval is the time between passes over the object graph, both for the
+	// polling scans and the in-page monitor (default: DefaultScanInterval,
+	// at least MinScanInterval)
+	Interval time.Duration
+	// MinValueLength and MaxValueLength, if positive, drop matches whose
+	// value is shorter or longer, counted in UTF-16 code units like
+	// JavaScript's String.length
+	MinValueLength int
+	MaxValueLength int
+	// MaxValueSize caps the bytes of a matched value that are reported
+	// (default: DefaultMaxValueSize, negative for no limit). Longer values are
+	// matched in full, reported cut with an ellipsis and Match.Truncated set.
+	MaxValueSize int
+	// ContextChars, if positive, reports up to this many characters of the
+	// value on either side of the matched text in Match.Context, along with
+	// the names of the properties next to the value
+	ContextChars int
+	// MaxMatches, if positive, stops the scan once it has found this many
+	// matches. MaxMatchesPerPattern, if positive, keeps only the first
+	// matches of each pattern and stops the scan once every pattern has
+	// that many. A stopped scan sets Stats.StoppedEarly.
+	MaxMatches           int
+	MaxMatchesPerPattern int
+	// MaxDedupEntries, if positive, caps the matches remembered to
+	// deduplicate repeats, forgetting the least recently seen ones, and
+	// likewise the matches whose sightings are counted and the paths whose
+	// values EmitOnChange tracks. Memory stays bounded on long scans, but a
+	// forgotten match seen again is reported again, and a forgotten path
+	// does not report its next change.
+	MaxDedupEntries int
+	// StreamMatches leaves matches out of the slice Scan returns, they are
+	// only passed to OnMatch, so long scans do not accumulate them. With
+	// DedupByValue, repeat values are then dropped instead of adding to the
+	// Paths of a match already passed on.
+	StreamMatches bool
+	// DedupBy selects how repeat matches are collapsed, DedupByPath (the
+	// default) or DedupByValue
+	DedupBy string
+	// EmitOnChange tracks the latest value at each path and reports a match
+	// whenever it changes, even back to a value reported before, with the
+	// value it replaced as Match.Previous. A change is seen by the next pass.
+	EmitOnChange bool
+	// Retries is the number of times a failed navigation is re-attempted,
+	// with exponential backoff starting at one second
+	Retries int
+	// ChromeFlags are extra command line flags for the browser, e.g.
+	// {"lang": "de-DE"}. Flags the scanner relies on, such as headless, are
+	// rejected unless OverrideChromeFlags is set.
+	ChromeFlags         map[string]interface{}
+	OverrideChromeFlags bool
+	// Insecure ignores TLS certificate errors, such as self-signed
+	// certificates. Traffic to the page can then be intercepted unnoticed.
+	Insecure bool
+	// Proxy sends all browser traffic through a proxy, e.g.
+	// "http://127.0.0.1:8080"
+	Proxy string
+	// ProxyRules are Chrome proxy rules such as
+	// "https=127.0.0.1:8080;http=direct://", used instead of Proxy
+	ProxyRules string
+	// ProxyPAC is the URL or path of a proxy auto-config file, to pick a
+	// proxy per host. It cannot be combined with Proxy or ProxyRules.
+	ProxyPAC string
+	// HTTPVersion, if set, forces the HTTP version the browser speaks:
+	// HTTPVersion1 disables HTTP/2 and HTTP/3, HTTPVersion2 disables HTTP/3
+	// and HTTPVersion3 forces QUIC for the origin of the scanned URL, which
+	// must be https. By default Chrome negotiates the version per server.
+	HTTPVersion string
+	// RemoteChrome, if set, is the DevTools endpoint of a running browser
+	// to scan in instead of launching one, either its ws:// debugger URL or
+	// http://host:port. Each page opens in a new tab sharing the browser's
+	// cookies and storage. ChromeFlags, Insecure, the proxy options and
+	// HTTPVersion only apply to a launched browser and are ignored.
+	RemoteChrome string
+	// ChromePath is the Chrome or Chromium executable to launch, looked up
+	// on the PATH and in the usual install locations if empty
+	ChromePath string
+	// Debug logs diagnostics from the scanner and the injected monitor, to
+	// stderr unless Logger is set
+	Debug bool
+	// Profile reports where the time of the scan went in Stats.Profile
+	Profile bool
+	// Logger receives the diagnostics of the scan: warnings such as failed
+	// retries, and at debug level everything Debug enables (default:
+	// slog.Default())
+	Logger *slog.Logger
+	// ScreenshotDir, if set, receives a full page PNG whenever new matches
+	// are found
+	ScreenshotDir string
+	// ScreenshotAll also captures every page once after it loads, whether
+	// or not it has matches
+	ScreenshotAll bool
+	// PostLoadScript, if set, is JavaScript evaluated in the page once it
+	// has loaded, before it is scanned, e.g. to open the view that holds the
+	// secrets. A returned promise is awaited.
+	PostLoadScript string
+	// Actions are clicks and typing performed in order once the page has
+	// loaded, after PostLoadScript and before the first scan. A failed
+	// action is logged and skipped, unless Strict is set, which fails the
+	// scan.
+	Actions []Action
+	Strict  bool
+	// State, if set, suppresses the matches it holds from earlier runs and
+	// records the new ones
+	State *State
+	// Baseline, if set, suppresses the accepted findings it holds
+	Baseline *Baseline
+	// HAR, if set, records the network traffic of the page. One HAR can be
+	// shared by several scans, each adding a page.
+	HAR *HAR
+	// NoFollowRedirects fails the scan with ErrRedirected when the page
+	// answers with an HTTP redirect, before the redirected request is sent
+	NoFollowRedirects bool
+	// Limiter, if set, throttles the page load and actions. Waiting for the
+	// page load does not count towards Timeout, waiting for actions does.
+	Limiter *Limiter
+
+	// OnMatch is called for every new match as soon as it is found
+	OnMatch func(Match)
+	// OnScan is called after every pass over the object graph
+	OnScan func(Stats)
+}
+
+// Stats represents the statistics of a scan
+type Stats struct {
+	// ObjectsScanned is the number of objects visited by the latest pass
+	ObjectsScanned int `json:"objectsScanned"`
+	// MatchesFound is the number of unique matches, i.e. len of the
+	// matches returned by Scan unless Options.StreamMatches is set. Repeat
+	// detections of a match are not counted.
+	MatchesFound int `json:"matchesFound"`
+	// ExistingMatches is the number of matches the first pass found with
+	// Options.SinceStart, which are not reported
+	ExistingMatches int `json:"existingMatches"`
+	// PagesScanned is the number of pages that loaded successfully
+	PagesScanned int `json:"pagesScanned"`
+	// FinalURL is the URL of the scanned page once redirects, including
+	// client-side ones, are followed. It is the scanned URL when the page
+	// was not redirected.
+	FinalURL string `json:"finalUrl,omitempty"`
+	// TruncatedScans is the number of passes, and of pages whose monitor
+	// walk, cut short by the scan budget
+	TruncatedScans int `json:"truncatedScans"`
+	// CappedScans is the number of passes, and of pages whose monitor,
+	// that stopped collecting matches at Options.JSMatchCap
+	CappedScans int `json:"cappedScans"`
+	// StoppedEarly is the number of pages whose scan stopped before the
+	// timeout because it reached Options.MaxMatches or MaxMatchesPerPattern
+	StoppedEarly int `json:"stoppedEarly"`
+	// Duration is the time spent scanning
+	Duration time.Duration `json:"duration"`
+	// Screenshots lists the paths of all screenshots taken
+	Screenshots []string `json:"screenshots,omitempty"`
+	// FailedInterceptors names the live interceptors the monitor could not
+	// install because the page froze or replaced the built-in, such as
+	// Reflect.set. Values they would catch are still found by scan passes.
+	FailedInterceptors []string `json:"failedInterceptors,omitempty"`
+	// DisabledPatterns names the patterns that were slow over too many
+	// values, likely backtracking catastrophically in the browser, and were
+	// no longer used for the rest of the page
+	DisabledPatterns []string `json:"disabledPatterns,omitempty"`
+	// Profile is the time spent in each phase of the scan, with
+	// Options.Profile
+	Profile *Profile `json:"profile,omitempty"`
+}
+
+// MarshalJSON encodes the duration in seconds
+func (s Stats) MarshalJSON() ([]byte, error) {
+	type stats Stats
+	return json.Marshal(struct {
+		stats
+		Duration float64 `json:"duration"`
+	}{stats(s), s.Duration.Seconds()})
+}
+
+// logger returns the logger of a scan
+func (opts Options) logger() *slog.Logger {
+	if opts.Logger != nil {
+		return opts.Logger
+	}
+	if opts.Debug {
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return slog.Default()
+}
+
+// scanMatch is a match as reported by the injected scripts
+type scanMatch struct {
+	Pattern     string `json:"pattern"`
+	Path        string `json:"path"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+	// Captured is the first capture group of the pattern
+	Captured string `json:"captured"`
+	// Context surrounds the match with Options.ContextChars
+	Context *MatchContext `json:"context"`
+	// Match is the matched text of a truncated value
+	Match     string `json:"match"`
+	Truncated bool   `json:"truncated"`
+	// Frame is the URL of the frame a pushed match was seen in, empty for
+	// the page itself
+	Frame string `json:"frame"`
+	// MatchCapReached marks the notice the monitor pushes in place of a
+	// match once it stops pushing them at the match cap
+	MatchCapReached bool `json:"matchCapReached"`
+	// WalkTruncated marks the notice the monitor pushes the first time its
+	// walk of the object graph is cut short by the scan budget
+	WalkTruncated bool `json:"walkTruncated"`
+}
+
+// pushedMatches is the number of matches pushed by the monitoring script
+// that can wait to be recorded. Further ones are dropped, to be found by
+// the next pass instead.
+const pushedMatches = 100
+
+// scanResponse is the JSON document returned by the scan script
+type scanResponse struct {
+	Matches []scanMatch `json:"matches"`
+	Stats   struct {
+		ObjectsScanned int  `json:"objectsScanned"`
+		MatchesFound   int  `json:"matchesFound"`
+		Truncated      bool `json:"truncated"`
+		// MatchCapReached is set once the pass holds the match cap
+		MatchCapReached bool `json:"matchCapReached"`
+		// SlowPatterns counts the slow evaluations of each pattern
+		SlowPatterns map[string]int `json:"slowPatterns"`
+	} `json:"stats"`
+	Error string `json:"error"`
+	// Pattern names the pattern the browser could not compile, failing the
+	// pass with Error
+	Pattern string `json:"pattern"`
+}
+
+// ErrPatternCompile is returned when a pattern that compiles in Go is
+// rejected by the JavaScript engine of the browser, so no pass could run
+var ErrPatternCompile = errors.New("pattern does not compile in the browser")
+
+// searchMonitor returns a monitor running the searches of opts, along with
+// the included and excluded pattern names that matched no pattern
+func searchMonitor(opts Options) (*ObjectMonitor, []string, error) {
+	monitor := NewObjectMonitor()
+	if opts.NoDefaultPatterns {
+		monitor.ClearPatterns()
+	}
+	for _, p := range opts.Patterns {
+		if err := monitor.AddPattern(p.Name, p.Pattern, p.Description); err != nil {
+			return nil, nil, err
+		}
+		if p.Severity != "" {
+			if err := monitor.SetSeverity(p.Name, p.Severity); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	unknown := monitor.SelectPatterns(opts.IncludePatterns, opts.ExcludePatterns)
+	for _, value := range append([]string{opts.CustomString}, opts.CustomStrings...) {
+		if value == "" {
+			continue
+		}
+		if opts.IgnoreCase {
+			monitor.AddCustomStringIgnoreCase(value)
+		} else {
+			monitor.AddCustomString(value)
+		}
+	}
+	for _, pattern := range opts.CustomRegexes {
+		if err := monitor.AddCustomRegex(pattern); err != nil {
+			return nil, nil, err
+		}
+	}
+	return monitor, unknown, nil
+}
+
+// ActivePatterns returns the searches a scan with opts runs, after the
+// default, config and custom patterns are combined and selected: the custom
+// searches in the order they were added if any, otherwise the patterns
+// sorted by name. Patterns less severe than opts.MinSeverity are left out,
+// and the included and excluded names that matched no pattern are returned.
+func ActivePatterns(opts Options) ([]Pattern, []string, error) {
+	if opts.MinSeverity != "" && opts.MinSeverity.Rank() == 0 {
+		return nil, nil, fmt.Errorf("unknown severity %q", opts.MinSeverity)
+	}
+	monitor, unknown, err := searchMonitor(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var patterns []Pattern
+	for _, name := range monitor.SearchNames() {
+		search, ok := monitor.patterns[name]
+		if len(monitor.custom) > 0 {
+			i := slices.IndexFunc(monitor.custom, func(c customSearch) bool { return c.name == name })
+			search, ok = monitor.custom[i].monitoredPattern, true
+		}
+		if !ok || (opts.MinSeverity != "" && !search.severity.AtLeast(opts.MinSeverity)) {
+			continue
+		}
+		patterns = append(patterns, Pattern{Name: name, Pattern: search.pattern, Description: search.description, Severity: search.severity})
+	}
+	return patterns, unknown, nil
+}
+
+// Scan loads url in a headless browser and monitors its JavaScript objects
+// until opts.Timeout expires, or scans them once with opts.Once, returning
+// every unique match found.
+func Scan(ctx context.Context, url string, opts Options) ([]Match, Stats, error) {
+	monitor, unknown, err := searchMonitor(opts)
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	logger := opts.logger()
+	if len(unknown) > 0 {
+		logger.Warn("unknown patterns ignored", "patterns", unknown)
+	}
+	if opts.MaxDepth > 0 {
+		monitor.maxDepth = opts.MaxDepth
+	}
+	for _, path := range opts.IgnoredPaths {
+		monitor.ignoredPaths[path] = true
+	}
+	monitor.excludePaths = opts.ExcludePaths
+	monitor.contextChars = opts.ContextChars
+	monitor.foundMatches = newDedupSet(opts.MaxDedupEntries)
+	monitor.debug = opts.Debug || logger.Enabled(ctx, slog.LevelDebug)
+	monitor.scanStorage = opts.ScanStorage
+	monitor.scanDOM = opts.ScanDOM
+	monitor.deepScan = opts.DeepScan
+	monitor.decodeBase64 = opts.DecodeBase64
+	if opts.ScanBudget != 0 {
+		monitor.objectBudget = max(opts.ScanBudget, 0)
+	}
+	if opts.ScanTimeBudget != 0 {
+		monitor.timeBudget = max(opts.ScanTimeBudget, 0)
+	}
+	if opts.JSMatchCap != 0 {
+		monitor.matchCap = max(opts.JSMatchCap, 0)
+	}
+	if opts.Interval != 0 {
+		if opts.Interval < MinScanInterval {
+			return nil, Stats{}, fmt.Errorf("scan interval %s is below the minimum of %s", opts.Interval, MinScanInterval)
+		}
+		monitor.interval = opts.Interval
+	}
+	monitor.minValueLen = opts.MinValueLength
+	monitor.maxValueLen = opts.MaxValueLength
+	if opts.MaxValueSize != 0 {
+		monitor.maxValueSize = max(opts.MaxValueSize, 0)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 20 * time.Second
+	}
+	if opts.MinSeverity != "" && opts.MinSeverity.Rank() == 0 {
+		return nil, Stats{}, fmt.Errorf("unknown severity %q", opts.MinSeverity)
+	}
+	if opts.DedupBy != "" && opts.DedupBy != DedupByPath && opts.DedupBy != DedupByValue {
+		return nil, Stats{}, fmt.Errorf("unknown dedup mode %q", opts.DedupBy)
+	}
+	if opts.SinceStart && opts.Once {
+		return nil, Stats{}, errors.New("since start needs the page to be monitored and cannot be combined with once")
+	}
+	if opts.ScreenshotDir != "" {
+		if err := os.MkdirAll(opts.ScreenshotDir, 0o755); err != nil {
+			return nil, Stats{}, err
+		}
+	}
+
+	// Wait for the first page load before the clock starts
+	if err := opts.Limiter.wait(ctx); err != nil {
+		return nil, Stats{}, err
+	}
+
+	var allocCtx context.Context
+	var cancel context.CancelFunc
+	if opts.RemoteChrome != "" {
+		allocCtx, cancel = chromedp.NewRemoteAllocator(ctx, opts.RemoteChrome)
+	} else {
+		allocOpts, err := allocatorOptions(opts, url)
+		if err != nil {
+			return nil, Stats{}, err
+		}
+		allocCtx, cancel = chromedp.NewExecAllocator(ctx, allocOpts...)
+	}
+	defer cancel()
+
+	// Create a new context
+	ctx, cancel = chromedp.NewContext(allocCtx)
+	defer cancel()
+	browserCtx := ctx
+
+	// Set timeout
+	ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	// Stopped once the match limits are reached
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	// Forward the browser console, including the monitor diagnostics
+	if monitor.debug {
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch ev := ev.(type) {
+			case *runtime.EventConsoleAPICalled:
+				logger.Debug("browser console", "type", ev.Type, "message", consoleMessage(ev))
+			case *runtime.EventExceptionThrown:
+				logger.Debug("browser exception", "message", exceptionMessage(ev.ExceptionDetails))
+			}
+		})
+	}
+
+	// Track the execution contexts of iframes
+	var frames *frameTracker
+	if opts.ScanFrames {
+		frames = newFrameTracker()
+		chromedp.ListenTarget(ctx, frames.handle)
+	}
+
+	// Collect the source maps of the scripts the page runs
+	var sourceMaps *sourceMapTracker
+	if opts.ScanSourceMaps {
+		sourceMaps = newSourceMapTracker()
+		chromedp.ListenTarget(ctx, sourceMaps.handle)
+	}
+
+	// Record the network traffic. Bodies are fetched outside the
+	// monitoring window so the last responses are not lost to the timeout.
+	var recorder *harRecorder
+	if opts.HAR != nil {
+		recorder = opts.HAR.page(url)
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			id, ok := recorder.handle(ev)
+			if !ok {
+				return
+			}
+			go func() {
+				if err := recorder.fetchBody(browserCtx, id); err != nil {
+					logger.Debug("could not record response body", "request", id, "error", err)
+				}
+			}()
+		})
+	}
+
+	// Receive the matches the monitoring script pushes as its interceptors
+	// see them, to be recorded by the monitoring loop. They are received
+	// past the timeout, for the grace period.
+	pushed := make(chan scanResponse, pushedMatches)
+	var monitorCapped, monitorTruncated atomic.Bool
+	if !opts.Once {
+		chromedp.ListenTarget(browserCtx, func(ev interface{}) {
+			call, ok := ev.(*runtime.EventBindingCalled)
+			if !ok || call.Name != matchBinding {
+				return
+			}
+			var found scanMatch
+			if err := json.Unmarshal([]byte(call.Payload), &found); err != nil {
+				logger.Debug("could not parse pushed match", "error", err)
+				return
+			}
+			if found.MatchCapReached {
+				monitorCapped.Store(true)
+				return
+			}
+			if found.WalkTruncated {
+				monitorTruncated.Store(true)
+				return
+			}
+			found.Path = canonicalPath(found.Path)
+			if found.Frame != "" {
+				found.Path = framePath(found.Frame, found.Path)
+			}
+			select {
+			case pushed <- scanResponse{Matches: []scanMatch{found}}:
+			default:
+				logger.Debug("dropping pushed match, the next pass will find it", "pattern", found.Pattern, "path", found.Path)
+			}
+		})
+	}
+
+	// Log the HTTP version each document was fetched with, warning once
+	// when it is not the one forced
+	versionWarned := false
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		received, ok := ev.(*network.EventResponseReceived)
+		if !ok || received.Type != network.ResourceTypeDocument {
+			return
+		}
+		logger.Debug("document received", "url", received.Response.URL, "protocol", received.Response.Protocol)
+		version := negotiatedHTTPVersion(received.Response.Protocol)
+		if opts.HTTPVersion != "" && opts.RemoteChrome == "" && version != "" && version != opts.HTTPVersion && !versionWarned {
+			versionWarned = true
+			logger.Warn("document not fetched with the forced HTTP version", "url", received.Response.URL, "version", opts.HTTPVersion, "protocol", received.Response.Protocol)
+		}
+	})
+
+	// Follow the redirects of the page, stopping them if asked
+	redirects := newRedirectTracker(url, opts.NoFollowRedirects)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		redirects.handle(ctx, ev)
+	})
+
+	// Send the headers from the frames and popups with sessions of their own
+	var sessions *headerSessions
+	if len(opts.Headers) > 0 && opts.Content == "" && !strings.HasPrefix(url, "file:") {
+		sessions = newHeaderSessions(opts.Headers, opts.RemoteChrome == "", logger)
+		chromedp.ListenTarget(browserCtx, func(ev interface{}) {
+			sessions.handleTarget(browserCtx, ev)
+		})
+		chromedp.ListenBrowser(browserCtx, func(ev interface{}) {
+			sessions.handleBrowser(browserCtx, ev)
+		})
+	}
+
+	var matches []Match
+	var stats Stats
+	var profile Profile
+	start := time.Now()
+
+	// Index of the match recording each value when deduplicating by value,
+	// or the values passed on when streaming
+	valueIndex := newDedupMap[int](opts.MaxDedupEntries)
+	// Index of the match recording each path and value, to count sightings
+	matchIndex := newDedupMap[int](opts.MaxDedupEntries)
+	// Latest value reported at each path, for EmitOnChange
+	latest := newDedupMap[string](opts.MaxDedupEntries)
+	streamedValues := newDedupSet(opts.MaxDedupEntries)
+	reported := 0
+
+	// With SinceStart, set while the first pass is recorded, whose matches
+	// only seed the known ones. Values it found are not new at other paths.
+	seeding := false
+	existingValues := make(map[string]bool)
+
+	// Matches kept of each pattern, for MaxMatchesPerPattern
+	perPattern := make(map[string]int)
+	limitReached := func() bool {
+		if opts.MaxMatches > 0 && reported >= opts.MaxMatches {
+			return true
+		}
+		if opts.MaxMatchesPerPattern <= 0 {
+			return false
+		}
+		for _, name := range monitor.SearchNames() {
+			if perPattern[name] < opts.MaxMatchesPerPattern {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Capture the page, logging failures rather than aborting the scan
+	screenshot := func(ctx context.Context) string {
+		path, err := takeScreenshot(ctx, opts.ScreenshotDir, url)
+		if err != nil {
+			logger.Warn("screenshot failed", "url", url, "error", err)
+			return ""
+		}
+		stats.Screenshots = append(stats.Screenshots, path)
+		return path
+	}
+
+	// Record only new matches
+	record := func(ctx context.Context, response scanResponse) {
+		var shot string
+		for _, found := range response.Matches {
+			// Create a unique key for this secret
+			secretKey := found.Path + ":" + found.Value
+			// A value seen before is reported again when it replaced another
+			previous, changed := "", false
+			if opts.EmitOnChange {
+				var known bool
+				previous, known = latest.get(found.Path)
+				changed = known && previous != monitor.reportedValue(found)
+				latest.set(found.Path, monitor.reportedValue(found))
+			}
+			// The scripts already applied the length limits to a truncated
+			// value, in full
+			if monitor.foundMatches.has(secretKey) && !changed {
+				if i, ok := matchIndex.get(secretKey); ok {
+					matches[i].Count++
+					now := time.Now()
+					matches[i].LastSeen = &now
+				}
+				continue
+			}
+			if monitor.isIgnoredPath(found.Path) || (!found.Truncated && !monitor.inValueLengthRange(found.Value)) {
+				continue
+			}
+			severity := monitor.severityOf(found.Pattern)
+			if opts.MinSeverity != "" && !severity.AtLeast(opts.MinSeverity) {
+				continue
+			}
+			if opts.MaxMatchesPerPattern > 0 && perPattern[found.Pattern] >= opts.MaxMatchesPerPattern {
+				continue
+			}
+			checked := found.Value
+			if found.Truncated {
+				checked = found.Match
+			}
+			if !monitor.matchesPattern(found.Pattern, checked) {
+				logger.Debug("dropping match not matched by the Go pattern", "pattern", found.Pattern, "path", found.Path)
+				continue
+			}
+			detail, ok := validate(found.Pattern, checked)
+			if !ok {
+				continue
+			}
+			description := found.Description
+			if detail != "" {
+				description += " (" + detail + ")"
+			}
+			monitor.foundMatches.add(secretKey)
+			if opts.Baseline != nil && opts.Baseline.contains(found.Pattern, found.Path, monitor.reportedValue(found)) {
+				logger.Debug("skipping match accepted by the baseline", "pattern", found.Pattern, "path", found.Path)
+				continue
+			}
+			if seeding {
+				logger.Debug("skipping match already there before monitoring", "pattern", found.Pattern, "path", found.Path)
+				existingValues[found.Value] = true
+				stats.ExistingMatches++
+				continue
+			}
+			if opts.State != nil && opts.State.remember(url, found.Path, found.Value) {
+				logger.Debug("skipping match known from an earlier run", "pattern", found.Pattern, "path", found.Path)
+				continue
+			}
+
+			// A known value seen at a new path only adds to its paths
+			if opts.DedupBy == DedupByValue && !changed {
+				if existingValues[found.Value] {
+					continue
+				}
+				if opts.StreamMatches {
+					if streamedValues.has(found.Value) {
+						continue
+					}
+					streamedValues.add(found.Value)
+				} else if i, ok := valueIndex.get(found.Value); ok {
+					matches[i].Paths = append(matches[i].Paths, found.Path)
+					matches[i].Count++
+					now := time.Now()
+					matches[i].LastSeen = &now
+					matchIndex.set(secretKey, i)
+					continue
+				} else {
+					valueIndex.set(found.Value, len(matches))
+				}
+			}
+
+			// New matches from the same pass share one screenshot
+			if opts.ScreenshotDir != "" && shot == "" {
+				shot = screenshot(ctx)
+			}
+
+			match := Match{
+				URL:         url,
+				Pattern:     found.Pattern,
+				Path:        found.Path,
+				Value:       monitor.reportedValue(found),
+				Captured:    found.Captured,
+				Context:     found.Context,
+				Description: description,
+				Severity:    severity,
+				Timestamp:   time.Now(),
+				Screenshot:  shot,
+				Count:       1,
+			}
+			match.ID = MatchID(match.Pattern, match.Path, match.Value)
+			if changed {
+				match.Previous = previous
+			}
+			lastSeen := match.Timestamp
+			match.LastSeen = &lastSeen
+			if opts.DedupBy == DedupByValue {
+				match.Paths = []string{found.Path}
+			}
+			if match.Value != found.Value {
+				match.Truncated = true
+			}
+			if monitor.maxValueSize > 0 && len(match.Captured) > monitor.maxValueSize {
+				match.Captured = truncateValue(match.Captured, monitor.maxValueSize)
+				match.Truncated = true
+			}
+			if !opts.StreamMatches {
+				matchIndex.set(secretKey, len(matches))
+				matches = append(matches, match)
+			}
+			reported++
+			perPattern[found.Pattern]++
+			if opts.OnMatch != nil {
+				opts.OnMatch(match)
+			}
+			if limitReached() {
+				logger.Info("match limit reached, stopping the scan", "url", url)
+				stats.StoppedEarly = 1
+				stop()
+				break
+			}
+		}
+		stats.ObjectsScanned = response.Stats.ObjectsScanned
+		if response.Stats.Truncated {
+			stats.TruncatedScans++
+		}
+		if response.Stats.MatchCapReached {
+			if stats.CappedScans == 0 {
+				logger.Warn("in-page matches truncated, a pass reached the match cap", "url", url, "cap", monitor.matchCap)
+			}
+			stats.CappedScans++
+		}
+		stats.MatchesFound = reported
+		stats.Duration = time.Since(start)
+		if opts.OnScan != nil {
+			opts.OnScan(stats)
+		}
+	}
+
+	monitoringScript := monitor.GetMonitoringScript()
+	scanScript := monitor.GetScanScript()
+
+	// Decode the result of the scan script
+	parse := func(result string) (scanResponse, error) {
+		response, err := parseScanResponse(result)
+		if err != nil && !errors.Is(err, ErrPatternCompile) {
+			logger.Debug("scan script failed", "error", err)
+		}
+		return response, err
+	}
+
+	// Run a single pass over the object graph of every frame
+	scanFrames := func(ctx context.Context) (scanResponse, error) {
+		var result string
+		if err := chromedp.Evaluate(scanScript, &result).Do(ctx); err != nil {
+			logger.Debug("scan failed", "error", err)
+			return scanResponse{}, err
+		}
+		response, err := parse(result)
+		if err != nil || frames == nil {
+			return response, err
+		}
+
+		children, err := frames.childFrames(ctx, func(url string) {
+			logger.Debug("skipping inaccessible frame, likely cross-origin", "frame", url)
+		})
+		if err != nil {
+			logger.Debug("could not list frames", "error", err)
+			return response, nil
+		}
+		for _, f := range children {
+			if !domainAllowed(opts.Domains, f.url) {
+				continue
+			}
+			// Inject the monitor the first time a frame is seen
+			if f.inject && !opts.Once {
+				if _, err := evaluateIn(ctx, f.contextID, monitoringScript); err != nil {
+					logger.Debug("injecting into frame failed", "frame", f.url, "error", err)
+				}
+			}
+
+			result, err := evaluateIn(ctx, f.contextID, scanScript)
+			if err != nil {
+				logger.Debug("scan of frame failed", "frame", f.url, "error", err)
+				continue
+			}
+			frameResponse, err := parse(result)
+			if err != nil {
+				continue
+			}
+			for _, found := range frameResponse.Matches {
+				found.Path = framePath(f.url, found.Path)
+				response.Matches = append(response.Matches, found)
+			}
+			response.Stats.ObjectsScanned += frameResponse.Stats.ObjectsScanned
+			response.Stats.MatchesFound += frameResponse.Stats.MatchesFound
+			response.Stats.Truncated = response.Stats.Truncated || frameResponse.Stats.Truncated
+			response.Stats.MatchCapReached = response.Stats.MatchCapReached || frameResponse.Stats.MatchCapReached
+			for name, runs := range frameResponse.Stats.SlowPatterns {
+				if response.Stats.SlowPatterns == nil {
+					response.Stats.SlowPatterns = make(map[string]int)
+				}
+				response.Stats.SlowPatterns[name] += runs
+			}
+		}
+		return response, nil
+	}
+
+	// Scan the original sources of the source maps found since the last pass
+	scanSourceMaps := func(ctx context.Context) []scanMatch {
+		var found []scanMatch
+		for _, mapURL := range sourceMaps.take() {
+			if !strings.HasPrefix(mapURL, "data:") && !domainAllowed(opts.Domains, mapURL) {
+				continue
+			}
+			data, err := loadSourceMap(ctx, mapURL)
+			if err != nil {
+				logger.Warn("could not load source map", "url", mapURL, "error", err)
+				continue
+			}
+			var m sourceMap
+			if err := json.Unmarshal(data, &m); err != nil {
+				logger.Warn("could not parse source map", "url", mapURL, "error", err)
+				continue
+			}
+			m.eachSource(func(name, content string) {
+				found = append(found, monitor.findInText(sourceMapPrefix+name, content)...)
+			})
+		}
+		return found
+	}
+
+	// Run a pass, disabling the patterns that were slow too often for the
+	// passes that follow
+	slowRuns := make(map[string]int)
+	scan := func(ctx context.Context) (scanResponse, error) {
+		response, err := scanFrames(ctx)
+		if err == nil && sourceMaps != nil {
+			response.Matches = append(response.Matches, scanSourceMaps(ctx)...)
+		}
+		for name, runs := range response.Stats.SlowPatterns {
+			slowRuns[name] += runs
+			if slowRuns[name] < maxSlowRuns || slices.Contains(monitor.disabled, name) {
+				continue
+			}
+			logger.Warn("disabled a slow pattern, it likely backtracks catastrophically", "pattern", name, "url", url)
+			monitor.disabled = append(monitor.disabled, name)
+			stats.DisabledPatterns = append(stats.DisabledPatterns, name)
+			scanScript = monitor.GetScanScript()
+			monitoringScript = monitor.GetMonitoringScript()
+		}
+		return response, err
+	}
+
+	// Run the browser. Each phase is timed from the end of the previous one.
+	phaseStart := time.Now()
+	phase := func(d *time.Duration) {
+		now := time.Now()
+		*d += now.Sub(phaseStart)
+		phaseStart = now
+	}
+	err = chromedp.Run(ctx,
+		// Navigate to the target page, sending the headers with every request
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			// The browser starts with the first action
+			phase(&profile.Launch)
+			if err := redirects.enable(ctx); err != nil {
+				return err
+			}
+			if !opts.Once {
+				if err := runtime.AddBinding(matchBinding).Do(ctx); err != nil {
+					return err
+				}
+			}
+			if sourceMaps != nil {
+				if err := sourceMaps.enable(ctx); err != nil {
+					logger.Warn("could not watch for source maps", "error", err)
+				}
+			}
+			if opts.Content != "" {
+				return setContent(ctx, opts.Content)
+			}
+			if sessions != nil {
+				if err := sessions.enable(ctx); err != nil {
+					return err
+				}
+			}
+			err := navigate(ctx, url, opts.Headers, opts.Retries, opts.Limiter, logger)
+			if location := redirects.blockedRedirect(); location != "" {
+				return fmt.Errorf("%w to %s", ErrRedirected, location)
+			}
+			return err
+		}),
+
+		// Wait for the page to be fully loaded
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			phase(&profile.Navigation)
+			stats.PagesScanned++
+			if chain := redirects.redirects(); len(chain) > 1 {
+				logger.Debug("followed redirects", "chain", chain)
+			}
+			stats.FinalURL = url
+			// The blank page holding Content is not a redirect
+			var location string
+			if err := chromedp.Location(&location).Do(ctx); err == nil && opts.Content == "" && !sameURL(location, url) {
+				stats.FinalURL = location
+			}
+			if opts.ScreenshotDir != "" && opts.ScreenshotAll {
+				screenshot(ctx)
+			}
+			return nil
+		}),
+
+		// Run the user's setup script. A failing script does not stop the
+		// scan, the page may still hold secrets.
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if opts.PostLoadScript == "" {
+				return nil
+			}
+			err := chromedp.Evaluate(opts.PostLoadScript, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+				return p.WithAwaitPromise(true)
+			}).Do(ctx)
+			if err != nil {
+				logger.Warn("post-load script failed", "error", err)
+			}
+			return nil
+		}),
+
+		// Drive the page to the state to scan
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			defer phase(&profile.Setup)
+			return runActions(ctx, opts.Actions, opts.Strict, opts.Limiter, logger)
+		}),
+
+		// Inject our monitoring script, unless there is nothing to monitor
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if opts.Once {
+				return nil
+			}
+			defer phase(&profile.Injection)
+			var result string
+			if err := chromedp.Evaluate(monitoringScript, &result).Do(ctx); err != nil {
+				return err
+			}
+			stats.FailedInterceptors = failedInterceptors(result)
+			if len(stats.FailedInterceptors) > 0 {
+				logger.Warn("live monitoring degraded, could not install interceptors", "interceptors", stats.FailedInterceptors)
+			}
+			return nil
+		}),
+
+		// Check for credentials multiple times
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			passStart := time.Now()
+			response, err := scan(ctx)
+			profile.addPass(passStart, response)
+			if errors.Is(err, ErrPatternCompile) {
+				return err
+			}
+			if err != nil {
+				return nil
+			}
+			seeding = opts.SinceStart
+			if seeding && response.Stats.Truncated {
+				logger.Warn("first pass over budget, existing values it did not reach may be reported as new", "url", url)
+			}
+			record(ctx, response)
+			seeding = false
+			if opts.Once {
+				return nil
+			}
+
+			// Add a continuous monitoring loop
+			ticker := time.NewTicker(monitor.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					// Re-run the scan
+					passStart := time.Now()
+					response, err := scan(ctx)
+					profile.addPass(passStart, response)
+					if errors.Is(err, ErrPatternCompile) {
+						return err
+					}
+					if err != nil {
+						continue
+					}
+					record(ctx, response)
+
+				case response := <-pushed:
+					record(ctx, response)
+
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}),
+	)
+
+	// Record what the deadline cut off: the matches pushed but not recorded
+	// yet, and a final pass in place of the interrupted one
+	if opts.Grace > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) && stats.PagesScanned > 0 && !limitReached() {
+		graceCtx, cancel := context.WithTimeout(browserCtx, opts.Grace)
+		passStart := time.Now()
+		response, scanErr := scan(graceCtx)
+		profile.addPass(passStart, response)
+		for drained := false; !drained; {
+			select {
+			case pushedResponse := <-pushed:
+				record(graceCtx, pushedResponse)
+			default:
+				drained = true
+			}
+		}
+		if scanErr == nil {
+			record(graceCtx, response)
+		} else {
+			logger.Debug("final pass failed", "error", scanErr)
+		}
+		cancel()
+	}
+
+	if opts.RemoteChrome == "" {
+		err = launchError(err)
+	}
+
+	// Reaching the end of the monitoring window before the loop starts, for
+	// example while injecting the monitor, is not an error either
+	if errors.Is(err, context.DeadlineExceeded) && stats.PagesScanned > 0 {
+		err = nil
+	}
+	if stats.StoppedEarly > 0 && errors.Is(err, context.Canceled) {
+		err = nil
+	}
+
+	if recorder != nil {
+		recorder.wait()
+	}
+
+	if monitorCapped.Load() {
+		logger.Warn("in-page matches truncated, the monitor stopped pushing matches at the match cap", "url", url, "cap", monitor.matchCap)
+		stats.CappedScans++
+	}
+	if monitorTruncated.Load() {
+		logger.Warn("in-page monitor walk truncated, the object graph exceeds the scan budget", "url", url, "objects", monitor.objectBudget, "time", monitor.timeBudget)
+		stats.TruncatedScans++
+	}
+	stats.Duration = time.Since(start)
+	if opts.Profile {
+		stats.Profile = &profile
+	}
+	return matches, stats, err
+}
+
+// parseScanResponse decodes the result of the scan script, failing with
+// the error the script reported
+func parseScanResponse(result string) (scanResponse, error) {
+	var response scanResponse
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		return response, fmt.Errorf("could not parse scan result: %w", err)
+	}
+	if response.Pattern != "" {
+		return response, fmt.Errorf("%w: %q: %s", ErrPatternCompile, response.Pattern, response.Error)
+	}
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+	for i := range response.Matches {
+		response.Matches[i].Path = canonicalPath(response.Matches[i].Path)
+	}
+	return response, nil
+}
+
+// failedInterceptors lists the interceptors the monitoring script reported
+// as not installed
+func failedInterceptors(result string) []string {
+	var installed map[string]bool
+	if err := json.Unmarshal([]byte(result), &installed); err != nil {
+		return nil
+	}
+	var failed []string
+	for name, ok := range installed {
+		if !ok {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// reportedValue is the value of found as it is reported, cut to the maximum
+// value size
+func (m *ObjectMonitor) reportedValue(found scanMatch) string {
+	if m.maxValueSize > 0 && (found.Truncated || len(found.Value) > m.maxValueSize) {
+		return truncateValue(found.Value, m.maxValueSize)
+	}
+	return found.Value
+}
+
+// truncateValue cuts value to at most size bytes, on a character boundary,
+// and marks the cut with an ellipsis
+func truncateValue(value string, size int) string {
+	if len(value) > size {
+		for size > 0 && !utf8.RuneStart(value[size]) {
+			size--
+		}
+		value = value[:size]
+	}
+	return value + "…"
+}
@@ -0,0 +1,232 @@
+package objector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fixtureAuth is the header the fixture server requires
+const fixtureAuth = "Bearer fixture-token"
+
+// scanOrSkip scans target, skipping the test when no browser is installed
+func scanOrSkip(t *testing.T, target string, opts Options) ([]Match, Stats) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("scanning needs a browser, skipped in short mode")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout+30*time.Second)
+	defer cancel()
+	matches, stats, err := Scan(ctx, target, opts)
+	if errors.Is(err, ErrChromeNotFound) {
+		t.Skipf("no browser to scan with: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Scan(%s) failed: %v", target, err)
+	}
+	return matches, stats
+}
+
+// fixtureExpected reads the pattern and path of each match expected in the
+// fixture page
+func fixtureExpected(t *testing.T) []string {
+	t.Helper()
+	f, err := os.Open("testdata/fixture.expected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var expected []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			expected = append(expected, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return expected
+}
+
+func TestScanFixture(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/fixture.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first load fails with a server error to exercise the retries,
+	// and the page is only served with the header
+	var loads, unauthorized atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != fixtureAuth {
+			unauthorized.Add(1)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if loads.Add(1) == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	matches, stats := scanOrSkip(t, server.URL+"/", Options{
+		Headers: map[string]string{"Authorization": fixtureAuth},
+		Retries: 1,
+		Timeout: 3 * time.Second,
+	})
+
+	if n := unauthorized.Load(); n > 0 {
+		t.Errorf("%d requests for the page came without the Authorization header", n)
+	}
+	if n := loads.Load(); n != 2 {
+		t.Errorf("page loaded %d times, want 2: a failed attempt and its retry", n)
+	}
+	if stats.PagesScanned != 1 {
+		t.Errorf("PagesScanned = %d, want 1", stats.PagesScanned)
+	}
+
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.Pattern+"\t"+m.Path)
+		if m.URL != server.URL+"/" {
+			t.Errorf("match %s at %s has URL %q, want the scanned one", m.Pattern, m.Path, m.URL)
+		}
+	}
+	slices.Sort(got)
+	if want := fixtureExpected(t); !slices.Equal(got, want) {
+		t.Errorf("matches in the fixture:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestScanHeadersReachEveryRequest(t *testing.T) {
+	var mu sync.Mutex
+	auth := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth[r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		// localhost is another site than 127.0.0.1, so Chrome renders the
+		// frame and the popup out of process
+		other := "http://" + strings.Replace(r.Host, "127.0.0.1", "localhost", 1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<html><body><iframe src="%s/frame"></iframe><script>
+				setTimeout(function () { window.open("%s/popup") }, 300);
+				setTimeout(function () { location.href = "/second" }, 600);
+			</script></body></html>`, other, other)
+		case "/frame":
+			fmt.Fprint(w, `<html><body><script>
+				fetch("/frame-fetch");
+				setTimeout(function () { location.href = "/frame-second" }, 100);
+			</script></body></html>`)
+		default:
+			fmt.Fprint(w, "<html><body>ok</body></html>")
+		}
+	}))
+	defer server.Close()
+
+	scanOrSkip(t, server.URL+"/", Options{
+		Headers: map[string]string{"Authorization": fixtureAuth},
+		Timeout: 3 * time.Second,
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"/", "/second", "/frame", "/frame-fetch", "/frame-second", "/popup"} {
+		got, ok := auth[path]
+		if !ok {
+			t.Errorf("%s was never requested", path)
+		} else if got != fixtureAuth {
+			t.Errorf("%s was requested with Authorization %q, want %q", path, got, fixtureAuth)
+		}
+	}
+}
+
+func TestScanHostileCustomStrings(t *testing.T) {
+	// The page holds each value, encoded so the page itself stays intact,
+	// and reports under window.pwnedReport if a value ever ran as code in
+	// the injected scripts
+	values, err := json.Marshal(hostileValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := `<html><body><script>
+		window.planted = ` + string(values) + `;
+		setInterval(function () {
+			if (window.pwned) window.pwnedReport = "PWNED-" + "REPORT";
+		}, 50);
+	</script></body></html>`
+	server := servePage(t, page)
+
+	matches, _ := scanOrSkip(t, server.URL+"/", Options{
+		CustomStrings: append([]string{"PWNED-REPORT"}, hostileValues...),
+		Timeout:       2 * time.Second,
+	})
+
+	found := make(map[string]bool)
+	for _, m := range matches {
+		if strings.HasPrefix(m.Path, "window.pwned") {
+			t.Errorf("a custom string ran as code in the page, found %s", m.Path)
+		}
+		found[strings.TrimPrefix(m.Pattern, "Custom String: ")] = true
+	}
+	for _, value := range hostileValues {
+		if !found[value] {
+			t.Errorf("custom string %q not found in the page", value)
+		}
+	}
+}
+
+// servePage serves html as the only page of a test server
+func servePage(t *testing.T, html string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(html))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScanBudgetBoundsMonitorWalk(t *testing.T) {
+	// A wide, cyclic graph far over the object budget
+	server := servePage(t, `<html><body><script>
+		window.graph = [];
+		for (let i = 0; i < 20000; i++) {
+			const node = { id: "node" + i, parent: window.graph };
+			node.self = node;
+			window.graph.push(node);
+		}
+	</script></body></html>`)
+
+	// The interval outlasts the scan, so the first pass and the walk the
+	// monitor makes as it starts are the only ones
+	_, stats := scanOrSkip(t, server.URL+"/", Options{
+		ScanBudget: 100,
+		Interval:   time.Minute,
+		Timeout:    2 * time.Second,
+	})
+	if stats.TruncatedScans != 2 {
+		t.Errorf("TruncatedScans = %d, want 2: the first pass and the monitor walk", stats.TruncatedScans)
+	}
+}
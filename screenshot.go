@@ -0,0 +1,44 @@
+package objector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// unsafeFilenameChars matches characters that are not portable in filenames
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// screenshotName returns a filesystem safe PNG filename for url taken at t
+func screenshotName(url string, t time.Time) string {
+	name := url
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+	name = strings.Trim(unsafeFilenameChars.ReplaceAllString(name, "_"), "_.")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	if name == "" {
+		name = "page"
+	}
+	return name + "-" + t.Format("20060102-150405.000") + ".png"
+}
+
+// takeScreenshot captures the full page into dir and returns the file path
+func takeScreenshot(ctx context.Context, dir, url string) (string, error) {
+	var buf []byte
+	if err := chromedp.FullScreenshot(&buf, 100).Do(ctx); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, screenshotName(url, time.Now()))
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,950 @@
+package objector
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// maxObjectEntries caps how many properties or entries of a single
+	// object, array, Map or Set are visited
+	maxObjectEntries = 1000
+	// minNumberLength is the number of characters a number needs before it
+	// is checked against the patterns as a string
+	minNumberLength = 10
+	// minBase64Length is the number of characters a base64 looking string
+	// needs before it is decoded with DecodeBase64
+	minBase64Length = 24
+	// maxBase64Depth caps how many layers of base64 are decoded
+	maxBase64Depth = 3
+	// maxContextSiblings caps the sibling property names reported in the
+	// context of a match
+	maxContextSiblings = 20
+	// slowPatternTime is how long a pattern may take over a single value
+	// before the evaluation counts as slow. The time is only checked once
+	// the evaluation returns, JavaScript cannot interrupt a regular
+	// expression, so a pattern that never finishes is not stopped but
+	// hangs the page until the scan times out. compilePattern rejects the
+	// usual culprits before they reach the scripts.
+	slowPatternTime = 100 * time.Millisecond
+	// maxSlowRuns is the number of slow evaluations after which a pattern
+	// is disabled, as it likely backtracks catastrophically
+	maxSlowRuns = 3
+)
+
+// matchBinding is the function the monitoring script calls to push each
+// match it sees to the scanner
+const matchBinding = "__objectorMatch"
+
+// scriptConfig is the monitor configuration handed to the injected scripts
+type scriptConfig struct {
+	Patterns     []Pattern `json:"patterns"`
+	Custom       []Pattern `json:"customSearches"`
+	IgnoredPaths []string  `json:"ignoredPaths"`
+	ExcludePaths []string  `json:"excludePaths"`
+	MaxDepth     int       `json:"maxDepth"`
+	Debug        bool      `json:"debug"`
+	ScanStorage  bool      `json:"scanStorage"`
+	ScanDOM      bool      `json:"scanDOM"`
+	MinValueLen  int       `json:"minValueLength"`
+	MaxValueLen  int       `json:"maxValueLength"`
+	MaxValueSize int       `json:"maxValueSize"`
+	MaxEntries   int       `json:"maxEntries"`
+	MinNumberLen int       `json:"minNumberLength"`
+	DeepScan     bool      `json:"deepScan"`
+	DecodeBase64 bool      `json:"decodeBase64"`
+	MinBase64Len int       `json:"minBase64Length"`
+	Base64Depth  int       `json:"maxBase64Depth"`
+	ObjectBudget int       `json:"objectBudget"`
+	TimeBudget   int64     `json:"timeBudget"`
+	ScanInterval int64     `json:"scanInterval"`
+	Binding      string    `json:"binding"`
+	MaxDedup     int       `json:"maxDedupEntries"`
+	MatchCap     int       `json:"matchCap"`
+	ContextChars int       `json:"contextChars"`
+	MaxSiblings  int       `json:"maxContextSiblings"`
+	SlowPattern  int64     `json:"slowPatternTime"`
+	MaxSlowRuns  int       `json:"maxSlowRuns"`
+	Disabled     []string  `json:"disabledPatterns"`
+}
+
+// scriptConfig returns the JSON encoded configuration for the injected
+// scripts. User input such as patterns and custom searches must only reach
+// the scripts through this JSON, never by formatting it into JavaScript
+// source: json.Marshal output is always a valid JavaScript literal, with
+// quotes, backslashes, newlines, "<" and U+2028/U+2029 escaped.
+func (m *ObjectMonitor) scriptConfig() string {
+	config, err := json.Marshal(scriptConfig{
+		Patterns:     m.Patterns(),
+		Custom:       m.customPatterns(),
+		IgnoredPaths: m.IgnoredPaths(),
+		ExcludePaths: append([]string{}, m.excludePaths...),
+		MaxDepth:     m.maxDepth,
+		Debug:        m.debug,
+		ScanStorage:  m.scanStorage,
+		ScanDOM:      m.scanDOM,
+		MinValueLen:  m.minValueLen,
+		MaxValueLen:  m.maxValueLen,
+		MaxValueSize: m.maxValueSize,
+		MaxEntries:   maxObjectEntries,
+		MinNumberLen: minNumberLength,
+		DeepScan:     m.deepScan,
+		DecodeBase64: m.decodeBase64,
+		MinBase64Len: minBase64Length,
+		Base64Depth:  maxBase64Depth,
+		ObjectBudget: m.objectBudget,
+		TimeBudget:   m.timeBudget.Milliseconds(),
+		ScanInterval: m.interval.Milliseconds(),
+		Binding:      matchBinding,
+		MaxDedup:     m.foundMatches.keys.limit,
+		MatchCap:     m.matchCap,
+		ContextChars: m.contextChars,
+		MaxSiblings:  maxContextSiblings,
+		SlowPattern:  slowPatternTime.Milliseconds(),
+		MaxSlowRuns:  maxSlowRuns,
+		Disabled:     append([]string{}, m.disabled...),
+	})
+	if err != nil {
+		// Only strings and ints are encoded, so this cannot happen
+		panic(err)
+	}
+	return string(config)
+}
+
+// customPatterns returns the custom searches in the order they were added.
+// Each custom string is a quoted literal, so it matches only itself in the
+// scripts whatever characters it holds.
+func (m *ObjectMonitor) customPatterns() []Pattern {
+	custom := make([]Pattern, 0, len(m.custom))
+	for _, c := range m.custom {
+		custom = append(custom, Pattern{Name: c.name, Pattern: c.pattern, Description: c.description, Severity: c.severity})
+	}
+	return custom
+}
+
+// GetMonitoringScript returns the JavaScript code for monitoring. The
+// script evaluates to a JSON string mapping each interceptor it installs on
+// built-ins such as Object.defineProperty to whether it succeeded.
+func (m *ObjectMonitor) GetMonitoringScript() string {
+	return `(function() {` + pathRulesScript + monitoringScript + `
+		const monitor = new ObjectMonitor(` + m.scriptConfig() + `);
+
+		// Add patterns to monitor, custom searches replacing them
+		const searches = monitor.options.customSearches.length > 0 ?
+			monitor.options.customSearches : monitor.options.patterns;
+		for (const { name, pattern, description } of searches) {
+			if (!monitor.options.disabledPatterns.includes(name)) {
+				try {
+					monitor.addPattern(name, pattern, description);
+				} catch (e) {
+					// The scan pass reports it
+					console.warn('[ObjectMonitor] Could not compile the ' + name + ' pattern: ' + e.message);
+				}
+			}
+		}
+
+		// Start monitoring, reporting which interceptors could be installed
+		return JSON.stringify(monitor.start());
+	})();`
+}
+
+// GetScanScript returns the JavaScript code for a single pass over the
+// object graph. The script evaluates to a JSON string holding the matches
+// and scan statistics.
+func (m *ObjectMonitor) GetScanScript() string {
+	return `(function() {` + pathRulesScript + `
+		return (` + scanScript + `)(` + m.scriptConfig() + `);
+	})()`
+}
+
+const monitoringScript = `
+		class ObjectMonitor {
+			constructor(options = {}) {
+				this.options = options;
+				this.patterns = new Map();
+				this.ignoredPaths = options.ignoredPaths || [
+					'window.performance', 'window.localStorage', 'window.sessionStorage', 'window.indexedDB',
+					'window.webkitStorageInfo', 'window.chrome', 'window.document', 'window.history'
+				];
+				this.maxDepth = options.maxDepth || 10;
+				this.foundMatches = new Set();
+				this.slowRuns = new Map();
+				this.debug = options.debug || false;
+				this.scanInterval = null;
+				this.budget = { objects: 0, deadline: Infinity, truncated: false };
+				this.stats = {
+					objectsScanned: 0,
+					matchesFound: 0
+				};
+			}
+
+			addPattern(name, pattern, description = '') {
+				if (!(pattern instanceof RegExp)) {
+					pattern = new RegExp(pattern);
+				}
+				this.patterns.set(name, { pattern, description });
+				return this;
+			}
+
+			checkValue(value, path) {
+				if (typeof value !== 'string') return;
+				path = canonicalPath(path);
+				if (this.options.minValueLength && value.length < this.options.minValueLength) return;
+				if (this.options.maxValueLength && value.length > this.options.maxValueLength) return;
+
+				for (const [name, { pattern, description }] of this.patterns) {
+					const started = Date.now();
+					const matches = value.match(pattern);
+					if (Date.now() - started > this.options.slowPatternTime) {
+						this.slowPattern(name);
+					}
+					if (matches) {
+						const match = {
+							pattern: name,
+							path,
+							value,
+							matches,
+							description,
+							timestamp: new Date().toISOString()
+						};
+
+						const matchKey = path + ':' + value;
+						if (!this.foundMatches.has(matchKey)) {
+							if (this.options.matchCap && this.foundMatches.size >= this.options.matchCap) {
+								this.matchCapReached();
+								return;
+							}
+							this.foundMatches.add(matchKey);
+							// Forget the oldest match once over the limit
+							if (this.options.maxDedupEntries && this.foundMatches.size > this.options.maxDedupEntries) {
+								this.foundMatches.delete(this.foundMatches.values().next().value);
+							}
+							this.logMatch(match);
+						}
+					}
+				}
+			}
+
+			// slowPattern counts a slow evaluation of the named pattern and
+			// stops using it once it was slow too often
+			slowPattern(name) {
+				const runs = (this.slowRuns.get(name) || 0) + 1;
+				this.slowRuns.set(name, runs);
+				if (runs >= this.options.maxSlowRuns) {
+					this.patterns.delete(name);
+					console.warn('[ObjectMonitor] Disabled the ' + name + ' pattern, it was slow ' + runs + ' times');
+				}
+			}
+
+			// matchCapReached stops pushing matches once the monitor holds
+			// the match cap, so a page leaking endless secrets cannot grow
+			// foundMatches until the tab runs out of memory. The scanner is
+			// told once, and the scan passes still find what is missed.
+			matchCapReached() {
+				if (this.capped) return;
+				this.capped = true;
+				console.warn('[ObjectMonitor] Stopped reporting matches after ' + this.options.matchCap);
+				const push = window[this.options.binding];
+				if (typeof push === 'function') {
+					push(JSON.stringify({ matchCapReached: true }));
+				}
+			}
+
+			// walk scans the object graph within the budgets of a scan pass,
+			// so a huge or endlessly nested graph cannot freeze the tab every
+			// interval. The scanner is told once a walk is cut short.
+			walk() {
+				this.budget = {
+					objects: 0,
+					deadline: Date.now() + this.options.timeBudget,
+					truncated: false
+				};
+				this.scanObject(window, 'window');
+				if (this.budget.truncated && !this.walkTruncated) {
+					this.walkTruncated = true;
+					console.warn('[ObjectMonitor] Walk stopped at the scan budget after ' + this.budget.objects + ' objects');
+					const push = window[this.options.binding];
+					if (typeof push === 'function') {
+						push(JSON.stringify({ walkTruncated: true }));
+					}
+				}
+			}
+
+			overBudget() {
+				const budget = this.budget;
+				if (!budget.truncated &&
+					((this.options.objectBudget && budget.objects >= this.options.objectBudget) ||
+					(this.options.timeBudget && Date.now() > budget.deadline))) {
+					budget.truncated = true;
+					this.log('Walk budget exhausted after ' + budget.objects + ' objects');
+				}
+				return budget.truncated;
+			}
+
+			logMatch(match) {
+				const output = {
+					timestamp: match.timestamp,
+					pattern: match.pattern,
+					path: match.path,
+					value: match.value,
+					captured: match.matches[1],
+					description: match.description
+				};
+				if (this.options.contextChars) {
+					const start = match.matches.index;
+					const end = start + match.matches[0].length;
+					output.context = {
+						before: match.value.slice(Math.max(0, start - this.options.contextChars), start),
+						after: match.value.slice(end, end + this.options.contextChars)
+					};
+				}
+
+				console.log('%c[ObjectMonitor Match]', 'color: #ff0000; font-weight: bold');
+				console.table([output]);
+
+				// Push the match to the scanner right away, naming the frame
+				// it was seen in unless that is the page itself. Spreading
+				// rather than calling Object.assign keeps the match away from
+				// the interceptor, which would report its fields again, such
+				// as the name of a custom string search holding the string.
+				const push = window[this.options.binding];
+				if (typeof push === 'function') {
+					push(JSON.stringify({ ...output, frame: window.top === window ? '' : location.href }));
+				}
+
+				const event = new CustomEvent('objectMonitorMatch', { detail: match });
+				window.dispatchEvent(event);
+			}
+
+			// checkRequest checks the headers and body of a request the page
+			// sends, at paths like fetch:<url>@header:Authorization. Header
+			// names keep the case the page gave them unless it passed a
+			// Headers object, which lowercases them.
+			checkRequest(kind, url, headers, body) {
+				try {
+					url = new URL(url, location.href).href;
+				} catch (e) {
+					// Keep the URL as given
+				}
+				const prefix = kind + ':' + url;
+				if (headers) {
+					try {
+						const entries = headers instanceof Headers || Array.isArray(headers) ?
+							Array.from(headers) : Object.entries(headers);
+						for (const [name, value] of entries) {
+							this.checkValue(String(value), prefix + '@header:' + name);
+						}
+					} catch (e) {
+						// Ignore malformed headers, the request fails anyway
+					}
+				}
+				this.checkBody(body, prefix + '@body');
+				return prefix;
+			}
+
+			// checkBody checks a text request body, or each field of a form
+			// body at <path>:<name>. Binary bodies are left alone.
+			checkBody(body, path) {
+				if (typeof body === 'string') {
+					this.checkValue(body, path);
+				} else if (body instanceof URLSearchParams || (typeof FormData === 'function' && body instanceof FormData)) {
+					for (const [name, value] of body) {
+						if (typeof value === 'string') {
+							this.checkValue(value, path + ':' + name);
+						}
+					}
+				}
+			}
+
+			// install sets up one interceptor. A page that froze or wrapped the
+			// built-in it replaces only costs that interceptor, which is
+			// recorded in stats.interceptors either way.
+			install(name, setup) {
+				try {
+					setup();
+					this.stats.interceptors[name] = true;
+				} catch (e) {
+					this.stats.interceptors[name] = false;
+					console.warn('[ObjectMonitor] Could not install the ' + name + ' interceptor, continuing with limited monitoring: ' + e);
+				}
+			}
+
+			start() {
+				if (!window.__objectMonitorActive) {
+					try {
+						this.walk();
+						window.__objectMonitorActive = true;
+						this.log('Initial scan visited ' + this.stats.objectsScanned + ' objects');
+						this.stats.interceptors = {};
+
+						// Writing a frozen property throws in class code, a page
+						// accessor may also ignore the write, so check it took
+						const replace = (owner, prop, wrapper) => {
+							owner[prop] = wrapper;
+							if (owner[prop] !== wrapper) {
+								throw new Error(prop + ' is read-only');
+							}
+						};
+
+						this.install('String', () => {
+							const originalString = String;
+							const wrapper = function(value) {
+								const str = originalString(value);
+								monitor.checkValue(str, 'String constructor');
+								return str;
+							};
+							wrapper.prototype = originalString.prototype;
+							replace(window, 'String', wrapper);
+						});
+
+						this.install('Object.defineProperty', () => {
+							const originalDefineProperty = Object.defineProperty;
+							replace(Object, 'defineProperty', function(obj, prop, descriptor) {
+								if (descriptor && descriptor.value) {
+									if (typeof descriptor.value === 'string') {
+										monitor.checkValue(descriptor.value, obj.constructor ? obj.constructor.name + '.' + prop : 'Object.' + prop);
+									}
+								}
+								return originalDefineProperty.call(this, obj, prop, descriptor);
+							});
+						});
+
+						this.install('Object.defineProperties', () => {
+							const originalDefineProperties = Object.defineProperties;
+							replace(Object, 'defineProperties', function(obj, props) {
+								for (const [prop, descriptor] of Object.entries(props)) {
+									if (descriptor && descriptor.value) {
+										if (typeof descriptor.value === 'string') {
+											monitor.checkValue(descriptor.value, obj.constructor ? obj.constructor.name + '.' + prop : 'Object.' + prop);
+										}
+									}
+								}
+								return originalDefineProperties.call(this, obj, props);
+							});
+						});
+
+						this.install('Object.create', () => {
+							const originalCreate = Object.create;
+							replace(Object, 'create', function(proto, properties) {
+								const obj = originalCreate.call(this, proto, properties);
+								if (properties) {
+									for (const [prop, descriptor] of Object.entries(properties)) {
+										if (descriptor && descriptor.value) {
+											if (typeof descriptor.value === 'string') {
+												monitor.checkValue(descriptor.value, 'Object.create.' + prop);
+											}
+										}
+									}
+								}
+								return obj;
+							});
+						});
+
+						this.install('Object.assign', () => {
+							const originalAssign = Object.assign;
+							replace(Object, 'assign', function(target, ...sources) {
+								const result = originalAssign.call(this, target, ...sources);
+								for (const source of sources) {
+									for (const [prop, value] of Object.entries(source)) {
+										if (typeof value === 'string') {
+											monitor.checkValue(value, 'Object.assign.' + prop);
+										}
+									}
+								}
+								return result;
+							});
+						});
+
+						this.install('Reflect.set', () => {
+							const originalSet = Reflect.set;
+							replace(Reflect, 'set', function(target, prop, value) {
+								if (typeof value === 'string') {
+									monitor.checkValue(value, target.constructor ? target.constructor.name + '.' + prop : 'Object.' + prop);
+								}
+								return originalSet.call(this, target, prop, value);
+							});
+						});
+
+						// Secrets are often sent rather than stored, as an
+						// Authorization header or a token in a POST body
+						this.install('fetch', () => {
+							const originalFetch = window.fetch;
+							if (typeof originalFetch !== 'function') {
+								throw new Error('fetch is not supported');
+							}
+							replace(window, 'fetch', function(input, init) {
+								try {
+									const request = input instanceof Request ? input : null;
+									const hasBody = init && init.body !== undefined && init.body !== null;
+									const prefix = monitor.checkRequest('fetch', request ? request.url : String(input),
+										init && init.headers ? init.headers : request && request.headers,
+										hasBody ? init.body : null);
+									// The body of a Request can only be read from a copy
+									if (request && !hasBody && request.body && !request.bodyUsed) {
+										request.clone().text().then(text => monitor.checkValue(text, prefix + '@body'), () => {});
+									}
+								} catch (e) {
+									// Never break the request
+								}
+								return originalFetch.apply(this, arguments);
+							});
+						});
+
+						this.install('XMLHttpRequest', () => {
+							const proto = XMLHttpRequest.prototype;
+							const originalOpen = proto.open;
+							const originalSetRequestHeader = proto.setRequestHeader;
+							const originalSend = proto.send;
+							const urls = new WeakMap();
+							const prefix = xhr => {
+								let url = urls.get(xhr) || '';
+								try {
+									url = new URL(url, location.href).href;
+								} catch (e) {
+									// Keep the URL as given
+								}
+								return 'xhr:' + url;
+							};
+							replace(proto, 'open', function(method, url) {
+								urls.set(this, String(url));
+								return originalOpen.apply(this, arguments);
+							});
+							replace(proto, 'setRequestHeader', function(name, value) {
+								try {
+									monitor.checkValue(String(value), prefix(this) + '@header:' + name);
+								} catch (e) {
+									// Never break the request
+								}
+								return originalSetRequestHeader.apply(this, arguments);
+							});
+							replace(proto, 'send', function(body) {
+								try {
+									monitor.checkBody(body, prefix(this) + '@body');
+								} catch (e) {
+									// Never break the request
+								}
+								return originalSend.apply(this, arguments);
+							});
+						});
+
+						this.scanInterval = setInterval(() => {
+							this.walk();
+						}, this.options.scanInterval || 1000);
+
+						this.install('window proxy', () => {
+							const windowHandler = {
+								get: (target, prop) => {
+									const value = target[prop];
+									if (typeof value === 'string') {
+										monitor.checkValue(value, 'window.' + String(prop));
+									}
+									return value;
+								},
+								set: (target, prop, value) => {
+									if (typeof value === 'string') {
+										monitor.checkValue(value, 'window.' + String(prop));
+									}
+									return Reflect.set(target, prop, value);
+								}
+							};
+
+							const windowProxy = new Proxy(window, windowHandler);
+							Object.defineProperty(window, '__proto__', {
+								get: () => windowProxy.__proto__,
+								set: (value) => {
+									windowProxy.__proto__ = value;
+									return true;
+								},
+								configurable: true
+							});
+						});
+
+						this.install('global proxy', () => {
+							const globalHandler = {
+								set: (target, prop, value) => {
+									if (typeof value === 'string') {
+										monitor.checkValue(value, 'global.' + String(prop));
+									}
+									return Reflect.set(target, prop, value);
+								}
+							};
+
+							const globalObject = Function('return this')();
+							const globalProxy = new Proxy(globalObject, globalHandler);
+							Object.defineProperty(globalObject, '__proto__', {
+								get: () => globalProxy.__proto__,
+								set: (value) => {
+									globalProxy.__proto__ = value;
+									return true;
+								},
+								configurable: true
+							});
+						});
+
+						console.log('%c[ObjectMonitor] Started monitoring all objects', 'color: #00ff00');
+					} catch (e) {
+						console.error('[ObjectMonitor] Failed to start:', e);
+					}
+				}
+				return this.stats.interceptors || {};
+			}
+
+			scanObject(obj, path = 'window', depth = 0, visited = new Set()) {
+				if (this.overBudget()) return;
+				if (depth > this.maxDepth) return;
+				if (!obj || typeof obj !== 'object') return;
+				if (visited.has(obj)) return;
+				if (isIgnoredPath(this.ignoredPaths, path) || isExcludedPath(this.options.excludePaths, path)) {
+					this.log('Skipped ignored path ' + path);
+					return;
+				}
+
+				// Typed arrays hold raw bytes and can be huge
+				if (ArrayBuffer.isView(obj)) return;
+
+				visited.add(obj);
+				this.stats.objectsScanned++;
+				this.budget.objects++;
+
+				let entries = 0;
+				try {
+					for (const prop in obj) {
+						if (++entries > this.options.maxEntries) {
+							this.log('Stopped after ' + this.options.maxEntries + ' entries of ' + path);
+							break;
+						}
+						try {
+							const value = obj[prop];
+							const newPath = path + '.' + prop;
+
+							if (typeof value === 'string') {
+								this.checkValue(value, newPath);
+							} else if (value && typeof value === 'object') {
+								this.scanObject(value, newPath, depth + 1, visited);
+							}
+						} catch (e) {}
+					}
+				} catch (e) {}
+			}
+
+			getStats() {
+				return this.stats;
+			}
+
+			log(message) {
+				if (this.debug) {
+					console.debug('[ObjectMonitor] ' + message);
+				}
+			}
+		}
+`
+
+const scanScript = `function(config) {
+	try {
+		let matches = [];
+		let visited = new Set();
+		let stats = {
+			objectsScanned: 0,
+			matchesFound: 0,
+			truncated: false,
+			matchCapReached: false
+		};
+		const deadline = Date.now() + config.timeBudget;
+
+		// A pattern JavaScript cannot compile fails the pass, naming it
+		const compile = ({ name, pattern, description }) => {
+			try {
+				return { name: name, pattern: new RegExp(pattern), description: description };
+			} catch (e) {
+				e.pattern = name;
+				throw e;
+			}
+		};
+
+		// Custom searches, if any, replace the patterns
+		const patterns = (config.customSearches.length > 0 ? config.customSearches : config.patterns)
+			.filter(({ name }) => !config.disabledPatterns.includes(name))
+			.map(compile);
+
+		// Patterns that were slow over a value, likely backtracking, are
+		// counted and skipped for the rest of the pass once too slow
+		stats.slowPatterns = {};
+		function slow(name) {
+			stats.slowPatterns[name] = (stats.slowPatterns[name] || 0) + 1;
+			if (stats.slowPatterns[name] === config.maxSlowRuns) {
+				log('Disabled the ' + name + ' pattern for this pass, it was slow ' + config.maxSlowRuns + ' times');
+			}
+		}
+
+		// Long runs of the base64 alphabet, standard or URL-safe
+		const base64Value = /^(?:[A-Za-z0-9+/]+|[A-Za-z0-9_-]+)={0,2}$/;
+
+		// Decode value if it is base64 for UTF-8 text, decoding anything
+		// else gives binary that is dropped
+		function decodeBase64(value) {
+			if (value.length < config.minBase64Length || value.length % 4 === 1 || !base64Value.test(value)) return null;
+			try {
+				const binary = atob(value.replace(/-/g, '+').replace(/_/g, '/'));
+				const bytes = Uint8Array.from(binary, c => c.charCodeAt(0));
+				const text = new TextDecoder('utf-8', { fatal: true }).decode(bytes);
+				return /[\x00-\x08\x0e-\x1f\x7f]/.test(text) ? null : text;
+			} catch (e) {
+				return null;
+			}
+		}
+
+		// Names of the other properties of the object a value was read from
+		function siblingNames(owner) {
+			const names = [];
+			try {
+				for (const prop in owner.object) {
+					if (prop === owner.key) continue;
+					if (names.length >= config.maxContextSiblings) break;
+					names.push(prop);
+				}
+			} catch (e) {
+				// Ignore object access errors
+			}
+			return names;
+		}
+
+		// owner is the object and key the value was read from, if any
+		function checkValue(value, path, depth = 0, owner = null) {
+			if (typeof value !== 'string' || stats.matchCapReached) return;
+			path = canonicalPath(path);
+			if (config.minValueLength && value.length < config.minValueLength) return;
+			if (config.maxValueLength && value.length > config.maxValueLength) return;
+
+			for (const { name, pattern, description } of patterns) {
+				if (stats.slowPatterns[name] >= config.maxSlowRuns) continue;
+				const started = Date.now();
+				const found = value.match(pattern);
+				if (Date.now() - started > config.slowPatternTime) {
+					slow(name);
+				}
+				if (found) {
+					stats.matchesFound++;
+					const match = {
+						pattern: name,
+						path: path,
+						value: value,
+						description: description
+					};
+					// The first capture group, for patterns that pick the
+					// secret out of its surrounding text
+					if (typeof found[1] === 'string') {
+						match.captured = found[1];
+					}
+					if (config.contextChars) {
+						const end = found.index + found[0].length;
+						match.context = {
+							before: value.slice(Math.max(0, found.index - config.contextChars), found.index),
+							after: value.slice(end, end + config.contextChars)
+						};
+						if (owner) {
+							match.context.siblings = siblingNames(owner);
+						}
+					}
+					// Only send the start of a huge value, with the matched
+					// text for the checks made in Go
+					if (config.maxValueSize && value.length > config.maxValueSize) {
+						match.value = value.slice(0, config.maxValueSize);
+						match.match = found[0];
+						match.truncated = true;
+					}
+					matches.push(match);
+					if (config.matchCap && matches.length >= config.matchCap) {
+						stats.matchCapReached = true;
+						log('Match cap reached after ' + matches.length + ' matches');
+					}
+					return;
+				}
+			}
+
+			if (config.decodeBase64 && depth < config.maxBase64Depth) {
+				const decoded = decodeBase64(value);
+				if (decoded !== null) {
+					checkValue(decoded, 'base64-decoded(' + path + ')', depth + 1, owner);
+				}
+			}
+		}
+
+		function log(message) {
+			if (config.debug) {
+				console.debug('[ObjectMonitor] ' + message);
+			}
+		}
+
+		// Check primitives that can carry a secret and descend into objects
+		function visit(value, path, depth, owner = null) {
+			switch (typeof value) {
+				case 'string':
+					checkValue(value, path, 0, owner);
+					break;
+				case 'number':
+				case 'bigint':
+					const digits = String(value);
+					if (digits.length >= config.minNumberLength) {
+						checkValue(digits, path, 0, owner);
+					}
+					break;
+				case 'symbol':
+					if (value.description) {
+						checkValue(value.description, path, 0, owner);
+					}
+					break;
+				case 'object':
+					if (value) {
+						scanObject(value, path, depth + 1);
+					}
+					break;
+			}
+		}
+
+		// Printable form of a Map key for use in a path
+		function keyName(key) {
+			return key && typeof key === 'object' ? '[object]' : JSON.stringify(String(key));
+		}
+
+		// Stop the pass once it has visited too many objects or run too long
+		function overBudget() {
+			if (!stats.truncated &&
+				((config.objectBudget && stats.objectsScanned >= config.objectBudget) ||
+				(config.timeBudget && Date.now() > deadline))) {
+				stats.truncated = true;
+				log('Scan budget exhausted after ' + stats.objectsScanned + ' objects');
+			}
+			return stats.truncated;
+		}
+
+		function scanObject(obj, path = 'window', depth = 0) {
+			if (overBudget() || stats.matchCapReached) return;
+			if (depth > config.maxDepth) return;
+			if (!obj || typeof obj !== 'object') return;
+			if (visited.has(obj)) return;
+
+			if (isIgnoredPath(config.ignoredPaths, path) || isExcludedPath(config.excludePaths, path)) {
+				log('Skipped ignored path ' + path);
+				return;
+			}
+
+			// Typed arrays hold raw bytes and can be huge
+			if (ArrayBuffer.isView(obj)) return;
+
+			visited.add(obj);
+			stats.objectsScanned++;
+
+			let entries = 0;
+			try {
+				if (obj instanceof Map) {
+					for (const [key, value] of obj) {
+						if (++entries > config.maxEntries) break;
+						visit(key, path + '.keys()', depth);
+						visit(value, path + '.get(' + keyName(key) + ')', depth);
+					}
+				} else if (obj instanceof Set) {
+					for (const value of obj) {
+						visit(value, path + '[' + entries + ']', depth);
+						if (++entries >= config.maxEntries) break;
+					}
+				}
+			} catch (e) {
+				// Ignore iteration errors
+			}
+
+			const seen = config.deepScan ? new Set() : null;
+			try {
+				for (const prop in obj) {
+					if (++entries > config.maxEntries) {
+						log('Stopped after ' + config.maxEntries + ' entries of ' + path);
+						break;
+					}
+					if (seen) seen.add(prop);
+					try {
+						visit(obj[prop], path + '.' + prop, depth, { object: obj, key: prop });
+					} catch (e) {
+						// Ignore property access errors
+					}
+				}
+			} catch (e) {
+				// Ignore object access errors
+			}
+
+			// Non-enumerable and inherited properties, including getters
+			if (config.deepScan) {
+				try {
+					for (let proto = obj; proto && proto !== Object.prototype; proto = Object.getPrototypeOf(proto)) {
+						for (const prop of Object.getOwnPropertyNames(proto)) {
+							if (seen.has(prop)) continue;
+							seen.add(prop);
+							if (++entries > config.maxEntries) return;
+
+							const descriptor = Object.getOwnPropertyDescriptor(proto, prop);
+							let value = descriptor.value;
+							if (descriptor.get) {
+								try {
+									value = descriptor.get.call(obj);
+								} catch (e) {
+									log('Getter ' + path + '.' + prop + ' threw: ' + e);
+									continue;
+								}
+							}
+							visit(value, path + '.' + prop, depth, { object: obj, key: prop });
+						}
+					}
+				} catch (e) {
+					log('Could not deep scan ' + path + ': ' + e);
+				}
+			}
+		}
+
+		// Get the global object
+		const globalObject = Function('return this')();
+
+		// Start scanning from global object
+		scanObject(globalObject);
+
+		// Web storage is skipped by the object walk, so enumerate it explicitly
+		if (config.scanStorage) {
+			for (const name of ['localStorage', 'sessionStorage']) {
+				try {
+					const storage = globalObject[name];
+					for (let i = 0; i < storage.length; i++) {
+						const key = storage.key(i);
+						checkValue(storage.getItem(key), name + "['" + key.replace(/'/g, "\\'") + "']");
+					}
+				} catch (e) {
+					log('Could not read ' + name + ': ' + e.message);
+				}
+			}
+		}
+
+		// Attributes such as data-api-key and inline event handlers live in
+		// the DOM, not the object graph. Elements are numbered per tag name
+		// in document order, so dom:div[3] is the fourth div.
+		if (config.scanDOM && globalObject.document) {
+			try {
+				const counts = new Map();
+				for (const element of globalObject.document.querySelectorAll('*')) {
+					if (overBudget()) break;
+					const tag = element.localName;
+					const index = counts.get(tag) || 0;
+					counts.set(tag, index + 1);
+					for (const attribute of element.attributes) {
+						checkValue(attribute.value, 'dom:' + tag + '[' + index + ']@' + attribute.name);
+					}
+				}
+			} catch (e) {
+				log('Could not scan the DOM: ' + e.message);
+			}
+		}
+		log('Scanned ' + stats.objectsScanned + ' objects, ' + stats.matchesFound + ' matches');
+
+		return JSON.stringify({
+			matches: matches,
+			stats: stats
+		});
+	} catch (e) {
+		return JSON.stringify({ error: e.message, pattern: e.pattern || '' });
+	}
+}`
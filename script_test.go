@@ -0,0 +1,182 @@
+package objector
+
+import (
+	"encoding/json"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// hostileValues are custom strings that break out of a JavaScript string,
+// comment or script element when embedded unescaped
+var hostileValues = []string{
+	`"; window.pwned = true; "`,
+	`'); window.pwned = true; ('`,
+	`\"; window.pwned = true; //`,
+	"`${window.pwned = true}`",
+	"line\nbreak\r\n",
+	"sep\u2028arator\u2029",
+	`</script><script>window.pwned = true</script>`,
+	`*/ window.pwned = true; /*`,
+	`<!-- <script>`,
+}
+
+func TestCustomPatterns(t *testing.T) {
+	m := NewObjectMonitor()
+	m.AddCustomString(`a"b\c`)
+	m.AddCustomStringIgnoreCase("Key")
+	if err := m.AddCustomRegex(`tok_[0-9]+`); err != nil {
+		t.Fatal(err)
+	}
+	m.AddCustomString(`a"b\c`)
+
+	got := m.customPatterns()
+	want := []struct {
+		name, pattern string
+	}{
+		{`Custom String: a"b\c`, `a"b\\c`},
+		{"Custom String: Key", "[Kk\u212A][eE][yY]"},
+		{"Custom Regex: tok_[0-9]+", `tok_[0-9]+`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("customPatterns returned %d searches, want %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Name != w.name || got[i].Pattern != w.pattern {
+			t.Errorf("customPatterns()[%d] = %q, %q, want %q, %q", i, got[i].Name, got[i].Pattern, w.name, w.pattern)
+		}
+	}
+}
+
+func TestCustomStringMatchesOnlyItself(t *testing.T) {
+	values := []string{`a.b`, `(x)+`, `[^]`, `$1\`, `a|b`, `</script>`, "line\nbreak"}
+	for _, value := range values {
+		m := NewObjectMonitor()
+		m.AddCustomString(value)
+		re := regexp.MustCompile(m.customPatterns()[0].Pattern)
+		if !re.MatchString("before " + value + " after") {
+			t.Errorf("custom string %q does not match itself", value)
+		}
+		if loc := re.FindStringIndex(value + value); loc == nil || loc[1]-loc[0] != len(value) {
+			t.Errorf("custom string %q matched %v of a repeat, want exactly itself", value, loc)
+		}
+	}
+}
+
+func TestScriptConfigRoundTrip(t *testing.T) {
+	m := NewObjectMonitor()
+	value := "q\"uote \\slash </script>   new\nline"
+	m.AddCustomString(value)
+
+	var config scriptConfig
+	if err := json.Unmarshal([]byte(m.scriptConfig()), &config); err != nil {
+		t.Fatalf("scriptConfig is not valid JSON: %v", err)
+	}
+	if len(config.Custom) != 1 || config.Custom[0].Name != "Custom String: "+value {
+		t.Errorf("custom searches after a round trip = %v", config.Custom)
+	}
+	if config.Custom[0].Pattern != regexp.QuoteMeta(value) {
+		t.Errorf("custom pattern after a round trip = %q, want %q", config.Custom[0].Pattern, regexp.QuoteMeta(value))
+	}
+}
+
+func TestScriptConfigIsSafeLiteral(t *testing.T) {
+	m := NewObjectMonitor()
+	for _, value := range hostileValues {
+		m.AddCustomString(value)
+	}
+	m.ignoredPaths[`window["</script>"]`] = true
+	m.excludePaths = []string{"window.\n*"}
+	config := m.scriptConfig()
+
+	for _, unsafe := range []string{"</script", "<!--", "\n", "\r", "\u2028", "\u2029"} {
+		if strings.Contains(config, unsafe) {
+			t.Errorf("scriptConfig holds %q unescaped", unsafe)
+		}
+	}
+	var decoded scriptConfig
+	if err := json.Unmarshal([]byte(config), &decoded); err != nil {
+		t.Fatalf("scriptConfig is not valid JSON: %v", err)
+	}
+	for i, value := range hostileValues {
+		if decoded.Custom[i].Name != "Custom String: "+value {
+			t.Errorf("custom search %d decoded as %q, want the name of %q", i, decoded.Custom[i].Name, value)
+		}
+	}
+}
+
+func TestScriptsParse(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is needed to parse the scripts")
+	}
+	m := NewObjectMonitor()
+	for _, value := range hostileValues {
+		m.AddCustomString(value)
+	}
+	scripts := map[string]string{"monitoring": m.GetMonitoringScript(), "scan": m.GetScanScript()}
+	for name, script := range scripts {
+		// Compiling without running shows the config stayed a literal
+		cmd := exec.Command(node, "-e", "new Function(require('fs').readFileSync(0, 'utf8'))")
+		cmd.Stdin = strings.NewReader(script)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s script does not parse: %v\n%s", name, err, out)
+		}
+	}
+}
+
+func TestPathRulesScriptMatchesGo(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is needed to run the path rules script")
+	}
+	var cases struct {
+		Rules    [][2]string `json:"rules"`
+		Segments [][2]string `json:"segments"`
+	}
+	for _, tt := range pathRuleTests {
+		cases.Rules = append(cases.Rules, [2]string{tt.rule, tt.path})
+	}
+	for _, tt := range segmentTests {
+		cases.Segments = append(cases.Segments, [2]string{tt.glob, tt.path})
+	}
+	input, err := json.Marshal(cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same cases through the functions of the injected scripts
+	script := pathRulesScript + `
+		const cases = JSON.parse(require('fs').readFileSync(0, 'utf8'));
+		process.stdout.write(JSON.stringify({
+			rules: cases.rules.map(([rule, path]) => matchesPathRule(rule, path)),
+			segments: cases.segments.map(([glob, path]) => matchSegments(glob.split('.'), path ? path.split('.') : []))
+		}));`
+	cmd := exec.Command(node, "-e", script)
+	cmd.Stdin = strings.NewReader(string(input))
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running the path rules script failed: %v", err)
+	}
+	var got struct {
+		Rules    []bool `json:"rules"`
+		Segments []bool `json:"segments"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("could not parse %q: %v", out, err)
+	}
+	if len(got.Rules) != len(pathRuleTests) || len(got.Segments) != len(segmentTests) {
+		t.Fatalf("script answered %d and %d cases, want %d and %d", len(got.Rules), len(got.Segments), len(pathRuleTests), len(segmentTests))
+	}
+	for i, tt := range pathRuleTests {
+		if got.Rules[i] != tt.want {
+			t.Errorf("script matchesPathRule(%q, %q) = %t, want %t", tt.rule, tt.path, got.Rules[i], tt.want)
+		}
+	}
+	for i, tt := range segmentTests {
+		if got.Segments[i] != tt.want {
+			t.Errorf("script matchSegments(%q, %q) = %t, want %t", tt.glob, tt.path, got.Segments[i], tt.want)
+		}
+	}
+}
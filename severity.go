@@ -0,0 +1,48 @@
+package objector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity ranks how damaging the exposure of a secret is
+type Severity string
+
+// Severity levels, from most to least severe
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+)
+
+// DefaultSeverity is given to patterns and custom searches without one
+const DefaultSeverity = SeverityMedium
+
+// Severities lists the levels from most to least severe
+var Severities = []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow}
+
+// ParseSeverity returns the level named s, ignoring case
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if severity.Rank() == 0 {
+		return "", fmt.Errorf("unknown severity %q, expected critical, high, medium or low", s)
+	}
+	return severity, nil
+}
+
+// Rank orders the levels from 1 for low to 4 for critical. Unknown levels
+// rank 0.
+func (s Severity) Rank() int {
+	for i, severity := range Severities {
+		if s == severity {
+			return len(Severities) - i
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether s is as severe as min or more
+func (s Severity) AtLeast(min Severity) bool {
+	return s.Rank() >= min.Rank()
+}
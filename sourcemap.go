@@ -0,0 +1,211 @@
+package objector
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/debugger"
+	cdpio "github.com/chromedp/cdproto/io"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+)
+
+// sourceMapPrefix starts the paths of matches found in the original
+// sources of a source map
+const sourceMapPrefix = "sourcemap:"
+
+// maxSourceMapSize caps the bytes of a source map that are loaded
+const maxSourceMapSize = 32 << 20
+
+// sourceMapTracker collects the source maps of the scripts the page parses,
+// as reported by the Debugger domain. Each map is only handed out once.
+type sourceMapTracker struct {
+	mu      sync.Mutex
+	pending []string
+	seen    map[string]bool
+}
+
+func newSourceMapTracker() *sourceMapTracker {
+	return &sourceMapTracker{seen: make(map[string]bool)}
+}
+
+// enable starts reporting parsed scripts, including those already parsed.
+// Pauses are skipped so debugger statements in the page do not stop it.
+func (t *sourceMapTracker) enable(ctx context.Context) error {
+	if _, err := debugger.Enable().Do(ctx); err != nil {
+		return err
+	}
+	return debugger.SetSkipAllPauses(true).Do(ctx)
+}
+
+// handle records the source map of a parsed script, resolved against the
+// script URL
+func (t *sourceMapTracker) handle(ev interface{}) {
+	parsed, ok := ev.(*debugger.EventScriptParsed)
+	if !ok || parsed.SourceMapURL == "" {
+		return
+	}
+	mapURL := parsed.SourceMapURL
+	if !strings.HasPrefix(mapURL, "data:") {
+		base, err := url.Parse(parsed.URL)
+		ref, refErr := url.Parse(mapURL)
+		if err == nil && refErr == nil {
+			mapURL = base.ResolveReference(ref).String()
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.seen[mapURL] {
+		t.seen[mapURL] = true
+		t.pending = append(t.pending, mapURL)
+	}
+}
+
+// take returns the source maps found since the last call
+func (t *sourceMapTracker) take() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := t.pending
+	t.pending = nil
+	return pending
+}
+
+// sourceMap is the part of a source map holding the original sources. An
+// index map holds its maps in sections instead.
+type sourceMap struct {
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent"`
+	Sections       []struct {
+		Map *sourceMap `json:"map"`
+	} `json:"sections"`
+}
+
+// eachSource calls f with the name and content of every original source
+// the map embeds
+func (m *sourceMap) eachSource(f func(name, content string)) {
+	for i, content := range m.SourcesContent {
+		if content == nil {
+			continue
+		}
+		name := fmt.Sprintf("sources[%d]", i)
+		if i < len(m.Sources) && m.Sources[i] != "" {
+			name = m.Sources[i]
+		}
+		f(name, *content)
+	}
+	for _, section := range m.Sections {
+		if section.Map != nil {
+			section.Map.eachSource(f)
+		}
+	}
+}
+
+// loadSourceMap decodes a data: URL, or has the browser load mapURL for the
+// page, with its cookies and without CORS checks as DevTools does
+func loadSourceMap(ctx context.Context, mapURL string) ([]byte, error) {
+	if strings.HasPrefix(mapURL, "data:") {
+		return decodeDataURL(mapURL)
+	}
+	tree, err := page.GetFrameTree().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := network.LoadNetworkResource(mapURL, &network.LoadNetworkResourceOptions{IncludeCredentials: true}).
+		WithFrameID(tree.Frame.ID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !resource.Success {
+		if resource.NetErrorName != "" {
+			return nil, errors.New(resource.NetErrorName)
+		}
+		return nil, fmt.Errorf("server responded with status %d", int(resource.HTTPStatusCode))
+	}
+	defer cdpio.Close(resource.Stream).Do(ctx)
+
+	var data []byte
+	for {
+		// Read.Do drops whether the chunk is base64 encoded
+		var chunk cdpio.ReadReturns
+		if err := cdp.Execute(ctx, cdpio.CommandRead, cdpio.Read(resource.Stream), &chunk); err != nil {
+			return nil, err
+		}
+		if chunk.Base64encoded {
+			decoded, err := base64.StdEncoding.DecodeString(chunk.Data)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, decoded...)
+		} else {
+			data = append(data, chunk.Data...)
+		}
+		if len(data) > maxSourceMapSize {
+			return nil, fmt.Errorf("larger than %d bytes", maxSourceMapSize)
+		}
+		if chunk.EOF {
+			return data, nil
+		}
+	}
+}
+
+// decodeDataURL returns the payload of a data: URL
+func decodeDataURL(dataURL string) ([]byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !ok {
+		return nil, errors.New("malformed data URL")
+	}
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	text, err := url.PathUnescape(payload)
+	return []byte(text), err
+}
+
+// findInText returns the matches of the active searches in text, a file
+// rather than a value of the object graph, so each match reports the
+// matched text as its value
+func (m *ObjectMonitor) findInText(path, text string) []scanMatch {
+	var found []scanMatch
+	for _, name := range m.SearchNames() {
+		if slices.Contains(m.disabled, name) {
+			continue
+		}
+		search, ok := m.patterns[name]
+		if len(m.custom) > 0 {
+			i := slices.IndexFunc(m.custom, func(c customSearch) bool { return c.name == name })
+			search, ok = m.custom[i].monitoredPattern, true
+		}
+		if !ok {
+			continue
+		}
+		for _, loc := range search.re.FindAllStringSubmatchIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			match := scanMatch{Pattern: name, Path: path, Value: text[loc[0]:loc[1]], Description: search.description}
+			if len(loc) >= 4 && loc[2] >= 0 {
+				match.Captured = text[loc[2]:loc[3]]
+			}
+			if m.contextChars > 0 {
+				// A rune is at most 4 bytes, so the windows hold enough of them
+				window := 4*m.contextChars + 3
+				before := []rune(text[max(loc[0]-window, 0):loc[0]])
+				after := []rune(text[loc[1]:min(loc[1]+window, len(text))])
+				match.Context = &MatchContext{
+					Before: string(before[max(len(before)-m.contextChars, 0):]),
+					After:  string(after[:min(len(after), m.contextChars)]),
+				}
+			}
+			found = append(found, match)
+		}
+	}
+	return found
+}
@@ -0,0 +1,108 @@
+package objector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// State is the set of matches reported by earlier runs, so scheduled scans
+// only report newly exposed secrets. Matches are stored as hashes of their
+// URL, path and value, never the values themselves.
+type State struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// stateFile is the JSON document a State is saved as
+type stateFile struct {
+	Version int      `json:"version"`
+	Matches []string `json:"matches"`
+}
+
+// NewState returns an empty state
+func NewState() *State {
+	return &State{seen: make(map[string]bool)}
+}
+
+// LoadState reads a state saved by Save. A missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	state := NewState()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file stateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("parsing %s: unsupported state version %d", path, file.Version)
+	}
+	for _, key := range file.Matches {
+		state.seen[key] = true
+	}
+	return state, nil
+}
+
+// Save writes the state to path, replacing it atomically
+func (s *State) Save(path string) error {
+	s.mu.Lock()
+	file := stateFile{Version: 1, Matches: make([]string, 0, len(s.seen))}
+	for key := range s.seen {
+		file.Matches = append(file.Matches, key)
+	}
+	s.mu.Unlock()
+	sort.Strings(file.Matches)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Len returns the number of matches in the state
+func (s *State) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.seen)
+}
+
+// remember adds the match at path with value found on url, reporting
+// whether it was already known
+func (s *State) remember(url, path, value string) bool {
+	sum := sha256.Sum256([]byte(url + "\x00" + path + "\x00" + value))
+	key := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return true
+	}
+	s.seen[key] = true
+	return false
+}
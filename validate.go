@@ -0,0 +1,95 @@
+package objector
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// validator re-checks a reported value, returning false to drop the match.
+// The detail, if any, is appended to the match description.
+type validator func(value string) (detail string, ok bool)
+
+// validators re-check the values reported for a pattern on the Go side,
+// dropping matches the in-page regex cannot rule out on its own
+var validators = map[string]validator{
+	"AWS Secret Key": func(value string) (string, bool) {
+		return "", isAWSSecretKey(value)
+	},
+	"JWT Token": validateJWT,
+}
+
+// validate reports whether value is a plausible match for the named pattern
+func validate(pattern, value string) (string, bool) {
+	if validator, ok := validators[pattern]; ok {
+		return validator(value)
+	}
+	return "", true
+}
+
+var base64Run = regexp.MustCompile(`[A-Za-z0-9/+]+`)
+
+// isAWSSecretKey reports whether value holds a 40 character base64 run
+// mixing upper case, lower case and digits, which rules out hex digests
+// and most identifiers
+func isAWSSecretKey(value string) bool {
+	for _, run := range base64Run.FindAllString(value, -1) {
+		if len(run) == 40 &&
+			strings.ContainsAny(run, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+			strings.ContainsAny(run, "abcdefghijklmnopqrstuvwxyz") &&
+			strings.ContainsAny(run, "0123456789") {
+			return true
+		}
+	}
+	return false
+}
+
+var jwtCandidate = regexp.MustCompile(`eyJ[A-Za-z0-9_=-]+\.[A-Za-z0-9_=-]+\.?[A-Za-z0-9_.+/=-]*`)
+
+// validateJWT checks that value holds a token whose header decodes to a JSON
+// object with an alg field, and describes its issuer and expiry
+func validateJWT(value string) (string, bool) {
+	for _, token := range jwtCandidate.FindAllString(value, -1) {
+		segments := strings.Split(token, ".")
+
+		var header struct {
+			Alg *string `json:"alg"`
+		}
+		if err := decodeJWTSegment(segments[0], &header); err != nil || header.Alg == nil {
+			continue
+		}
+		details := []string{"alg: " + *header.Alg}
+
+		var claims struct {
+			Iss string   `json:"iss"`
+			Exp *float64 `json:"exp"`
+		}
+		if err := decodeJWTSegment(segments[1], &claims); err == nil {
+			if claims.Iss != "" {
+				details = append(details, "iss: "+claims.Iss)
+			}
+			if claims.Exp != nil {
+				exp := time.Unix(int64(*claims.Exp), 0).UTC()
+				state := "valid"
+				if exp.Before(time.Now()) {
+					state = "expired"
+				}
+				details = append(details, fmt.Sprintf("exp: %s (%s)", exp.Format(time.RFC3339), state))
+			}
+		}
+		return strings.Join(details, ", "), true
+	}
+	return "", false
+}
+
+// decodeJWTSegment base64url decodes a JWT segment and parses it as JSON
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,78 @@
+package objector
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+// jwt joins the base64url encoded header and claims into a token
+func jwt(header, claims string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2lnbmF0dXJl"
+}
+
+func TestValidateJWT(t *testing.T) {
+	tests := []struct {
+		name, value, detail string
+		ok                  bool
+	}{
+		{"alg only", jwt(`{"alg":"HS256","typ":"JWT"}`, `{"sub":"1"}`), "alg: HS256", true},
+		{"alg none", jwt(`{"alg":"none"}`, `{}`), "alg: none", true},
+		{"issuer and expired", jwt(`{"alg":"RS256"}`, `{"iss":"https://auth.example","exp":1000000000}`),
+			"alg: RS256, iss: https://auth.example, exp: 2001-09-09T01:46:40Z (expired)", true},
+		{"valid expiry", jwt(`{"alg":"ES256"}`, `{"exp":4102444800}`), "alg: ES256, exp: 2100-01-01T00:00:00Z (valid)", true},
+		{"fractional expiry", jwt(`{"alg":"ES256"}`, `{"exp":4102444800.5}`), "alg: ES256, exp: 2100-01-01T00:00:00Z (valid)", true},
+		{"padded segments", jwt(`{"alg":"HS256"}`, `{}`) + "==", "alg: HS256", true},
+		{"in surrounding text", "Bearer " + jwt(`{"alg":"HS512"}`, `{}`) + ";", "alg: HS512", true},
+		{"claims not JSON", jwt(`{"alg":"HS256"}`, `not json`), "alg: HS256", true},
+		{"second token valid", jwt(`{"typ":"JWT"}`, `{}`) + " " + jwt(`{"alg":"HS384"}`, `{}`), "alg: HS384", true},
+		{"no alg", jwt(`{"typ":"JWT"}`, `{}`), "", false},
+		{"alg null", jwt(`{"alg":null}`, `{}`), "", false},
+		{"header not an object", jwt(`["alg"]`, `{}`), "", false},
+		{"header not JSON", "eyJhbGciOiJIUzI1NiJ9garbage.e30.sig", "", false},
+		{"bad base64", "eyJ!!!.e30.sig", "", false},
+		{"standard base64 in the header", "eyJhbGciOiJIUzI1NiJ9+/.e30.sig", "", false},
+		{"no token", "eyJ", "", false},
+	}
+	for _, tt := range tests {
+		detail, ok := validateJWT(tt.value)
+		if detail != tt.detail || ok != tt.ok {
+			t.Errorf("%s: validateJWT(%q) = %q, %t, want %q, %t", tt.name, tt.value, detail, ok, tt.detail, tt.ok)
+		}
+	}
+}
+
+func TestIsAWSSecretKey(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", true},
+		{`aws_secret_access_key = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"`, true},
+		{"Abcdefghij0123456789abcdefghij0123456789", true},
+		// Hex digests and single case or digit-free runs are not keys
+		{"da39a3ee5e6b4b0d3255bfef95601890afd80709", false},
+		{"DA39A3EE5E6B4B0D3255BFEF95601890AFD80709", false},
+		{"abcdefghijABCDEFGHIJabcdefghijABCDEFGHIJ", false},
+		{"abcdefghij0123456789abcdefghij0123456789", false},
+		// Only runs of exactly 40 characters count
+		{"wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKE", false},
+		{"wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEYS", false},
+		{"x wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY-y", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isAWSSecretKey(tt.value); got != tt.want {
+			t.Errorf("isAWSSecretKey(%q) = %t, want %t", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if _, ok := validate("AWS Secret Key", "da39a3ee5e6b4b0d3255bfef95601890afd80709"); ok {
+		t.Error("validate kept a hex digest as an AWS Secret Key")
+	}
+	if detail, ok := validate("Slack Token", "anything"); detail != "" || !ok {
+		t.Errorf("validate of a pattern without a validator = %q, %t, want it kept as is", detail, ok)
+	}
+}